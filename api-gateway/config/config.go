@@ -3,6 +3,9 @@ package config
 type Config struct {
 	Server   ServerConfig
 	Services ServicesConfig
+	Redis    RedisConfig
+	Storage  StorageConfig
+	Upload   UploadConfig
 }
 
 type ServerConfig struct {
@@ -15,6 +18,20 @@ type ServicesConfig struct {
 	OrderService   string
 }
 
+type RedisConfig struct {
+	Addr string // 保存 token 吊销记录的 Redis
+}
+
+type StorageConfig struct {
+	LocalDir string // 本地存储根目录
+	BaseURL  string // 静态文件访问前缀
+}
+
+type UploadConfig struct {
+	MaxImageSize  int64 // 单张图片大小上限（字节）
+	MaxImportSize int64 // 商品导入文件大小上限（字节）
+}
+
 var DefaultConfig = Config{
 	Server: ServerConfig{
 		Port: "8080",
@@ -24,4 +41,15 @@ var DefaultConfig = Config{
 		ProductService: "localhost:50052",
 		OrderService:   "localhost:50053",
 	},
+	Redis: RedisConfig{
+		Addr: "localhost:6379",
+	},
+	Storage: StorageConfig{
+		LocalDir: "uploads",
+		BaseURL:  "/static",
+	},
+	Upload: UploadConfig{
+		MaxImageSize:  5 << 20,
+		MaxImportSize: 20 << 20,
+	},
 }
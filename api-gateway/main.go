@@ -4,10 +4,15 @@ import (
 	"api-gateway/config"
 	"api-gateway/router"
 	"api-gateway/service"
+	"api-gateway/storage"
+	jwtmiddleware "common/middleware"
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+
+	"github.com/redis/go-redis/v9"
 )
 
 // @title E-commerce API Gateway
@@ -30,14 +35,29 @@ func main() {
 	}
 	defer productSvc.Close()
 
-	// TODO: 初始化 gRPC 订单服务客户端
-	// orderSvc, err := service.NewOrderService(config.DefaultConfig.Services.OrderService)
-	// if err != nil {
-	// 	log.Fatalf("Failed to connect to order service: %v", err)
-	// }
-	// defer orderSvc.Close()
+	// 初始化 gRPC 订单服务客户端
+	orderSvc, err := service.NewOrderService(config.DefaultConfig.Services.OrderService)
+	if err != nil {
+		log.Fatalf("Failed to connect to order service: %v", err)
+	}
+	defer orderSvc.Close()
+
+	// 连接 Redis，校验 token 时检查吊销记录
+	rdb := redis.NewClient(&redis.Options{Addr: config.DefaultConfig.Redis.Addr})
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer rdb.Close()
+	jwtmiddleware.SetTokenDenylist(rdb)
+
+	// 初始化图片存储，本地存储的文件由网关直接提供静态访问
+	store, err := storage.NewLocalStorage(config.DefaultConfig.Storage.LocalDir, config.DefaultConfig.Storage.BaseURL)
+	if err != nil {
+		log.Fatalf("Failed to init storage: %v", err)
+	}
 
-	r := router.SetupRouter(userSvc, productSvc)
+	r := router.SetupRouter(userSvc, productSvc, orderSvc, store)
+	r.Static(config.DefaultConfig.Storage.BaseURL, store.Root())
 
 	// 启动 HTTP 服务器
 	go func() {
@@ -39,10 +39,17 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// 内部服务 token 只用于服务之间的 gRPC 调用，不接受外部请求
+		if claims.Role == jwtmiddleware.RoleService {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
 
 		// 将用户信息存储到 context 中，方便后续 handler 使用
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
 
 		c.Next()
 	}
@@ -26,7 +26,27 @@ type OrderServiceClient interface {
 	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*GetOrderResponse, error)
 	ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error)
 	UpdateOrderStatus(ctx context.Context, in *UpdateOrderStatusRequest, opts ...grpc.CallOption) (*UpdateOrderStatusResponse, error)
+	// 已废弃：等同于 HideOrder，保留以兼容旧客户端
 	DeleteOrder(ctx context.Context, in *DeleteOrderRequest, opts ...grpc.CallOption) (*DeleteOrderResponse, error)
+	// 用户隐藏订单（仅已完成/已取消的订单）
+	HideOrder(ctx context.Context, in *HideOrderRequest, opts ...grpc.CallOption) (*HideOrderResponse, error)
+	// 管理员归档历史订单
+	ArchiveOrders(ctx context.Context, in *ArchiveOrdersRequest, opts ...grpc.CallOption) (*ArchiveOrdersResponse, error)
+	// 管理员恢复已归档订单
+	RestoreOrder(ctx context.Context, in *RestoreOrderRequest, opts ...grpc.CallOption) (*RestoreOrderResponse, error)
+	// 财务导出订单（流式返回，覆盖所有用户）
+	ExportOrders(ctx context.Context, in *ExportOrdersRequest, opts ...grpc.CallOption) (OrderService_ExportOrdersClient, error)
+	// 客服/管理员跨用户搜索订单
+	SearchOrders(ctx context.Context, in *SearchOrdersRequest, opts ...grpc.CallOption) (*SearchOrdersResponse, error)
+	// 秒杀：活动库存预热到 Redis，抢购成功后异步创建订单，客户端轮询抢购结果
+	CreateFlashSale(ctx context.Context, in *CreateFlashSaleRequest, opts ...grpc.CallOption) (*CreateFlashSaleResponse, error)
+	ListFlashSales(ctx context.Context, in *ListFlashSalesRequest, opts ...grpc.CallOption) (*ListFlashSalesResponse, error)
+	PurchaseFlashSale(ctx context.Context, in *PurchaseFlashSaleRequest, opts ...grpc.CallOption) (*PurchaseFlashSaleResponse, error)
+	GetFlashSaleResult(ctx context.Context, in *GetFlashSaleResultRequest, opts ...grpc.CallOption) (*GetFlashSaleResultResponse, error)
+	// 商品推荐：按订单中的共同购买统计（定时计算），只返回上架且有库存的商品
+	GetRelatedProducts(ctx context.Context, in *GetRelatedProductsRequest, opts ...grpc.CallOption) (*GetRelatedProductsResponse, error)
+	// 按用户的购买记录推荐商品，不足时补充热销商品；用户只能查询自己的推荐
+	GetRecommendationsForUser(ctx context.Context, in *GetRecommendationsForUserRequest, opts ...grpc.CallOption) (*GetRecommendationsForUserResponse, error)
 }
 
 type orderServiceClient struct {
@@ -82,6 +102,128 @@ func (c *orderServiceClient) DeleteOrder(ctx context.Context, in *DeleteOrderReq
 	return out, nil
 }
 
+func (c *orderServiceClient) HideOrder(ctx context.Context, in *HideOrderRequest, opts ...grpc.CallOption) (*HideOrderResponse, error) {
+	out := new(HideOrderResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/HideOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) ArchiveOrders(ctx context.Context, in *ArchiveOrdersRequest, opts ...grpc.CallOption) (*ArchiveOrdersResponse, error) {
+	out := new(ArchiveOrdersResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/ArchiveOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) RestoreOrder(ctx context.Context, in *RestoreOrderRequest, opts ...grpc.CallOption) (*RestoreOrderResponse, error) {
+	out := new(RestoreOrderResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/RestoreOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) ExportOrders(ctx context.Context, in *ExportOrdersRequest, opts ...grpc.CallOption) (OrderService_ExportOrdersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderService_ServiceDesc.Streams[0], "/proto.OrderService/ExportOrders", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderServiceExportOrdersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OrderService_ExportOrdersClient interface {
+	Recv() (*Order, error)
+	grpc.ClientStream
+}
+
+type orderServiceExportOrdersClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderServiceExportOrdersClient) Recv() (*Order, error) {
+	m := new(Order)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *orderServiceClient) SearchOrders(ctx context.Context, in *SearchOrdersRequest, opts ...grpc.CallOption) (*SearchOrdersResponse, error) {
+	out := new(SearchOrdersResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/SearchOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CreateFlashSale(ctx context.Context, in *CreateFlashSaleRequest, opts ...grpc.CallOption) (*CreateFlashSaleResponse, error) {
+	out := new(CreateFlashSaleResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/CreateFlashSale", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) ListFlashSales(ctx context.Context, in *ListFlashSalesRequest, opts ...grpc.CallOption) (*ListFlashSalesResponse, error) {
+	out := new(ListFlashSalesResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/ListFlashSales", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) PurchaseFlashSale(ctx context.Context, in *PurchaseFlashSaleRequest, opts ...grpc.CallOption) (*PurchaseFlashSaleResponse, error) {
+	out := new(PurchaseFlashSaleResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/PurchaseFlashSale", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetFlashSaleResult(ctx context.Context, in *GetFlashSaleResultRequest, opts ...grpc.CallOption) (*GetFlashSaleResultResponse, error) {
+	out := new(GetFlashSaleResultResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/GetFlashSaleResult", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetRelatedProducts(ctx context.Context, in *GetRelatedProductsRequest, opts ...grpc.CallOption) (*GetRelatedProductsResponse, error) {
+	out := new(GetRelatedProductsResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/GetRelatedProducts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetRecommendationsForUser(ctx context.Context, in *GetRecommendationsForUserRequest, opts ...grpc.CallOption) (*GetRecommendationsForUserResponse, error) {
+	out := new(GetRecommendationsForUserResponse)
+	err := c.cc.Invoke(ctx, "/proto.OrderService/GetRecommendationsForUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrderServiceServer is the server API for OrderService service.
 // All implementations must embed UnimplementedOrderServiceServer
 // for forward compatibility
@@ -90,7 +232,27 @@ type OrderServiceServer interface {
 	GetOrder(context.Context, *GetOrderRequest) (*GetOrderResponse, error)
 	ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error)
 	UpdateOrderStatus(context.Context, *UpdateOrderStatusRequest) (*UpdateOrderStatusResponse, error)
+	// 已废弃：等同于 HideOrder，保留以兼容旧客户端
 	DeleteOrder(context.Context, *DeleteOrderRequest) (*DeleteOrderResponse, error)
+	// 用户隐藏订单（仅已完成/已取消的订单）
+	HideOrder(context.Context, *HideOrderRequest) (*HideOrderResponse, error)
+	// 管理员归档历史订单
+	ArchiveOrders(context.Context, *ArchiveOrdersRequest) (*ArchiveOrdersResponse, error)
+	// 管理员恢复已归档订单
+	RestoreOrder(context.Context, *RestoreOrderRequest) (*RestoreOrderResponse, error)
+	// 财务导出订单（流式返回，覆盖所有用户）
+	ExportOrders(*ExportOrdersRequest, OrderService_ExportOrdersServer) error
+	// 客服/管理员跨用户搜索订单
+	SearchOrders(context.Context, *SearchOrdersRequest) (*SearchOrdersResponse, error)
+	// 秒杀：活动库存预热到 Redis，抢购成功后异步创建订单，客户端轮询抢购结果
+	CreateFlashSale(context.Context, *CreateFlashSaleRequest) (*CreateFlashSaleResponse, error)
+	ListFlashSales(context.Context, *ListFlashSalesRequest) (*ListFlashSalesResponse, error)
+	PurchaseFlashSale(context.Context, *PurchaseFlashSaleRequest) (*PurchaseFlashSaleResponse, error)
+	GetFlashSaleResult(context.Context, *GetFlashSaleResultRequest) (*GetFlashSaleResultResponse, error)
+	// 商品推荐：按订单中的共同购买统计（定时计算），只返回上架且有库存的商品
+	GetRelatedProducts(context.Context, *GetRelatedProductsRequest) (*GetRelatedProductsResponse, error)
+	// 按用户的购买记录推荐商品，不足时补充热销商品；用户只能查询自己的推荐
+	GetRecommendationsForUser(context.Context, *GetRecommendationsForUserRequest) (*GetRecommendationsForUserResponse, error)
 	mustEmbedUnimplementedOrderServiceServer()
 }
 
@@ -113,6 +275,39 @@ func (UnimplementedOrderServiceServer) UpdateOrderStatus(context.Context, *Updat
 func (UnimplementedOrderServiceServer) DeleteOrder(context.Context, *DeleteOrderRequest) (*DeleteOrderResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteOrder not implemented")
 }
+func (UnimplementedOrderServiceServer) HideOrder(context.Context, *HideOrderRequest) (*HideOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HideOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) ArchiveOrders(context.Context, *ArchiveOrdersRequest) (*ArchiveOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ArchiveOrders not implemented")
+}
+func (UnimplementedOrderServiceServer) RestoreOrder(context.Context, *RestoreOrderRequest) (*RestoreOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) ExportOrders(*ExportOrdersRequest, OrderService_ExportOrdersServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportOrders not implemented")
+}
+func (UnimplementedOrderServiceServer) SearchOrders(context.Context, *SearchOrdersRequest) (*SearchOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchOrders not implemented")
+}
+func (UnimplementedOrderServiceServer) CreateFlashSale(context.Context, *CreateFlashSaleRequest) (*CreateFlashSaleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateFlashSale not implemented")
+}
+func (UnimplementedOrderServiceServer) ListFlashSales(context.Context, *ListFlashSalesRequest) (*ListFlashSalesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFlashSales not implemented")
+}
+func (UnimplementedOrderServiceServer) PurchaseFlashSale(context.Context, *PurchaseFlashSaleRequest) (*PurchaseFlashSaleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurchaseFlashSale not implemented")
+}
+func (UnimplementedOrderServiceServer) GetFlashSaleResult(context.Context, *GetFlashSaleResultRequest) (*GetFlashSaleResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFlashSaleResult not implemented")
+}
+func (UnimplementedOrderServiceServer) GetRelatedProducts(context.Context, *GetRelatedProductsRequest) (*GetRelatedProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRelatedProducts not implemented")
+}
+func (UnimplementedOrderServiceServer) GetRecommendationsForUser(context.Context, *GetRecommendationsForUserRequest) (*GetRecommendationsForUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecommendationsForUser not implemented")
+}
 func (UnimplementedOrderServiceServer) mustEmbedUnimplementedOrderServiceServer() {}
 
 // UnsafeOrderServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -216,6 +411,207 @@ func _OrderService_DeleteOrder_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OrderService_HideOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HideOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).HideOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/HideOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).HideOrder(ctx, req.(*HideOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_ArchiveOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ArchiveOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).ArchiveOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/ArchiveOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).ArchiveOrders(ctx, req.(*ArchiveOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_RestoreOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).RestoreOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/RestoreOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).RestoreOrder(ctx, req.(*RestoreOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_ExportOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportOrdersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderServiceServer).ExportOrders(m, &orderServiceExportOrdersServer{stream})
+}
+
+type OrderService_ExportOrdersServer interface {
+	Send(*Order) error
+	grpc.ServerStream
+}
+
+type orderServiceExportOrdersServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderServiceExportOrdersServer) Send(m *Order) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _OrderService_SearchOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).SearchOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/SearchOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).SearchOrders(ctx, req.(*SearchOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CreateFlashSale_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateFlashSaleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CreateFlashSale(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/CreateFlashSale",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CreateFlashSale(ctx, req.(*CreateFlashSaleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_ListFlashSales_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFlashSalesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).ListFlashSales(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/ListFlashSales",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).ListFlashSales(ctx, req.(*ListFlashSalesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_PurchaseFlashSale_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurchaseFlashSaleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).PurchaseFlashSale(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/PurchaseFlashSale",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).PurchaseFlashSale(ctx, req.(*PurchaseFlashSaleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetFlashSaleResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFlashSaleResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetFlashSaleResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/GetFlashSaleResult",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetFlashSaleResult(ctx, req.(*GetFlashSaleResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetRelatedProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRelatedProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetRelatedProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/GetRelatedProducts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetRelatedProducts(ctx, req.(*GetRelatedProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetRecommendationsForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecommendationsForUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetRecommendationsForUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.OrderService/GetRecommendationsForUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetRecommendationsForUser(ctx, req.(*GetRecommendationsForUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // OrderService_ServiceDesc is the grpc.ServiceDesc for OrderService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -243,7 +639,53 @@ var OrderService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteOrder",
 			Handler:    _OrderService_DeleteOrder_Handler,
 		},
+		{
+			MethodName: "HideOrder",
+			Handler:    _OrderService_HideOrder_Handler,
+		},
+		{
+			MethodName: "ArchiveOrders",
+			Handler:    _OrderService_ArchiveOrders_Handler,
+		},
+		{
+			MethodName: "RestoreOrder",
+			Handler:    _OrderService_RestoreOrder_Handler,
+		},
+		{
+			MethodName: "SearchOrders",
+			Handler:    _OrderService_SearchOrders_Handler,
+		},
+		{
+			MethodName: "CreateFlashSale",
+			Handler:    _OrderService_CreateFlashSale_Handler,
+		},
+		{
+			MethodName: "ListFlashSales",
+			Handler:    _OrderService_ListFlashSales_Handler,
+		},
+		{
+			MethodName: "PurchaseFlashSale",
+			Handler:    _OrderService_PurchaseFlashSale_Handler,
+		},
+		{
+			MethodName: "GetFlashSaleResult",
+			Handler:    _OrderService_GetFlashSaleResult_Handler,
+		},
+		{
+			MethodName: "GetRelatedProducts",
+			Handler:    _OrderService_GetRelatedProducts_Handler,
+		},
+		{
+			MethodName: "GetRecommendationsForUser",
+			Handler:    _OrderService_GetRecommendationsForUser_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportOrders",
+			Handler:       _OrderService_ExportOrders_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/order.proto",
 }
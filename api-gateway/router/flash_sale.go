@@ -0,0 +1,113 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createFlashSaleHandler 创建秒杀活动，请求体为 {"product_id": 1, "sku_id": 0, "price": 9.9, "stock": 100, "start_at": "2026-11-11T20:00:00+08:00", "end_at": "2026-11-11T21:00:00+08:00"}，
+// 时间支持 RFC3339 或日期，日期形式的 end_at 包含当天
+func createFlashSaleHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			ProductID int64   `json:"product_id" binding:"required"`
+			SkuID     int64   `json:"sku_id"`
+			Price     float64 `json:"price" binding:"required"`
+			Stock     int32   `json:"stock" binding:"required"`
+			StartAt   string  `json:"start_at" binding:"required"`
+			EndAt     string  `json:"end_at" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		startAt, err := parseTimeParam(body.StartAt, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		endAt, err := parseTimeParam(body.EndAt, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := orderSvc.CreateFlashSale(authContext(c), &proto.CreateFlashSaleRequest{
+			ProductId: body.ProductID,
+			SkuId:     body.SkuID,
+			Price:     body.Price,
+			Stock:     body.Stock,
+			StartAt:   startAt,
+			EndAt:     endAt,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// listFlashSalesHandler 进行中和即将开始的秒杀活动及剩余库存
+func listFlashSalesHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp, err := orderSvc.ListFlashSales(authContext(c), &proto.ListFlashSalesRequest{})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// purchaseFlashSaleHandler 秒杀抢购，请求体为 {"ship_to_latitude": 31.23, "ship_to_longitude": 121.47}，
+// 抢到后订单异步创建，返回 202，之后通过结果接口轮询
+func purchaseFlashSaleHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		flashSaleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid flash sale id"})
+			return
+		}
+		var body struct {
+			ShipToLatitude  float64 `json:"ship_to_latitude"`
+			ShipToLongitude float64 `json:"ship_to_longitude"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		resp, err := orderSvc.PurchaseFlashSale(authContext(c), &proto.PurchaseFlashSaleRequest{
+			FlashSaleId:     flashSaleID,
+			ShipToLatitude:  body.ShipToLatitude,
+			ShipToLongitude: body.ShipToLongitude,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, resp)
+	}
+}
+
+// flashSaleResultHandler 查询当前用户的抢购结果
+func flashSaleResultHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		flashSaleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid flash sale id"})
+			return
+		}
+		resp, err := orderSvc.GetFlashSaleResult(authContext(c), &proto.GetFlashSaleResultRequest{FlashSaleId: flashSaleID})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
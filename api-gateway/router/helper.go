@@ -0,0 +1,37 @@
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authContext 将请求的 Authorization 头部透传到 gRPC metadata
+func authContext(c *gin.Context) context.Context {
+	md := metadata.Pairs("authorization", c.GetHeader("Authorization"))
+	return metadata.NewOutgoingContext(c.Request.Context(), md)
+}
+
+// httpStatusFromError 将 gRPC 错误码转换为 HTTP 状态码
+func httpStatusFromError(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,225 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"api-gateway/storage"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"common/middleware"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	thumbnailSize    = 300 // 缩略图最长边
+	thumbnailQuality = 85
+	maxImagePixels   = 50_000_000 // 像素数上限，防止小文件解码出超大图片
+)
+
+// imageExtensions 允许上传的图片类型及保存时使用的扩展名，类型根据文件内容判断
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// requireAdmin 校验当前用户是否为管理员，需在 AuthMiddleware 之后使用
+func requireAdmin(c *gin.Context) bool {
+	if c.GetString("role") != middleware.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return false
+	}
+	return true
+}
+
+// uploadProductImageHandler 上传商品图片：校验类型和大小，保存原图和缩略图后登记到商品图集
+func uploadProductImageHandler(productSvc *service.ProductService, store storage.Storage, maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		// 预留 multipart 头部的空间，超出部分直接拒绝读取
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		if fileHeader.Size > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("image must be smaller than %d bytes", maxSize)})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+			return
+		}
+		defer file.Close()
+		data := make([]byte, fileHeader.Size)
+		if _, err := io.ReadFull(file, data); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+			return
+		}
+
+		contentType := http.DetectContentType(data)
+		ext, ok := imageExtensions[contentType]
+		if !ok {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "only jpeg, png, gif and webp images are allowed"})
+			return
+		}
+		thumbnail, err := makeThumbnail(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid image"})
+			return
+		}
+
+		name, err := randomName()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save image"})
+			return
+		}
+		key := fmt.Sprintf("products/%d/%s%s", productID, name, ext)
+		thumbnailKey := fmt.Sprintf("products/%d/%s_thumb.jpg", productID, name)
+		ctx := c.Request.Context()
+		if err := store.Save(ctx, key, bytes.NewReader(data), contentType); err != nil {
+			log.Printf("Failed to save image %s: %v", key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save image"})
+			return
+		}
+		if err := store.Save(ctx, thumbnailKey, bytes.NewReader(thumbnail), "image/jpeg"); err != nil {
+			log.Printf("Failed to save thumbnail %s: %v", thumbnailKey, err)
+			deleteStoredFiles(ctx, store, key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save image"})
+			return
+		}
+
+		resp, err := productSvc.AddProductImage(authContext(c), &proto.AddProductImageRequest{
+			ProductId:    productID,
+			Url:          store.URL(key),
+			ThumbnailUrl: store.URL(thumbnailKey),
+			StorageKey:   key,
+			ThumbnailKey: thumbnailKey,
+		})
+		if err != nil {
+			deleteStoredFiles(ctx, store, key, thumbnailKey)
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// deleteProductImageHandler 删除商品图片及存储中的文件
+func deleteProductImageHandler(productSvc *service.ProductService, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		imageID, err := strconv.ParseInt(c.Param("image_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid image id"})
+			return
+		}
+		resp, err := productSvc.DeleteProductImage(authContext(c), &proto.DeleteProductImageRequest{ImageId: imageID})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		deleteStoredFiles(c.Request.Context(), store, resp.StorageKeys...)
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// reorderProductImagesHandler 调整商品图片顺序，请求体为 {"image_ids": [3, 1, 2]}
+func reorderProductImagesHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		var body struct {
+			ImageIDs []int64 `json:"image_ids" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := productSvc.ReorderProductImages(authContext(c), &proto.ReorderProductImagesRequest{
+			ProductId: productID,
+			ImageIds:  body.ImageIDs,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// makeThumbnail 生成等比缩放的 JPEG 缩略图，图片较小时不放大
+func makeThumbnail(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailSize || height > thumbnailSize {
+		if width >= height {
+			width, height = thumbnailSize, max(1, height*thumbnailSize/width)
+		} else {
+			width, height = max(1, width*thumbnailSize/height), thumbnailSize
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	// 透明背景在 JPEG 中会变黑，先铺白色
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func deleteStoredFiles(ctx context.Context, store storage.Storage, keys ...string) {
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
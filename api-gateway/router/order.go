@@ -0,0 +1,244 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushRows 导出时每写多少行刷新一次响应
+const exportFlushRows = 200
+
+// parseOrderStatuses 解析 status 查询参数，支持枚举名（PAID）或数字
+func parseOrderStatuses(values []string) ([]proto.OrderStatus, error) {
+	var statuses []proto.OrderStatus
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if n, ok := proto.OrderStatus_value[strings.ToUpper(v)]; ok {
+				statuses = append(statuses, proto.OrderStatus(n))
+				continue
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid status %q", v)
+			}
+			if _, ok := proto.OrderStatus_name[int32(n)]; !ok {
+				return nil, fmt.Errorf("invalid status %q", v)
+			}
+			statuses = append(statuses, proto.OrderStatus(n))
+		}
+	}
+	return statuses, nil
+}
+
+// parseTimeParam 解析时间范围参数，支持 RFC3339 或 2006-01-02；
+// 日期形式的结束时间包含当天，因此取次日零点
+func parseTimeParam(value string, end bool) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return value, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q", value)
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day.Format(time.RFC3339), nil
+}
+
+// exportOrdersHandler 流式导出订单，format=csv（默认）或 ndjson
+func exportOrdersHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "csv")
+		if format != "csv" && format != "ndjson" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+			return
+		}
+		from, err := parseTimeParam(c.Query("from"), false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeParam(c.Query("to"), true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		statuses, err := parseOrderStatuses(c.QueryArray("status"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		stream, err := orderSvc.ExportOrders(authContext(c), &proto.ExportOrdersRequest{
+			CreatedFrom:     from,
+			CreatedTo:       to,
+			Statuses:        statuses,
+			IncludeArchived: c.Query("include_archived") == "true",
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		// 先读取第一条，确保鉴权等错误能以正常的 HTTP 错误返回
+		first, err := stream.Recv()
+		if err != nil && err != io.EOF {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		filename := fmt.Sprintf("orders-%s.%s", time.Now().Format("20060102150405"), format)
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		if format == "csv" {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			c.Header("Content-Type", "application/x-ndjson")
+		}
+		c.Status(http.StatusOK)
+
+		w := newOrderExportWriter(c.Writer, format)
+		rows := 0
+		for order := first; order != nil; {
+			if err := w.write(order); err != nil {
+				log.Printf("Export orders write failed: %v", err)
+				return
+			}
+			if rows++; rows%exportFlushRows == 0 {
+				w.flush()
+			}
+			order, err = stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				// 响应头已发送，只能中断输出
+				log.Printf("Export orders stream failed: %v", err)
+				w.flush()
+				return
+			}
+		}
+		w.flush()
+	}
+}
+
+// searchOrdersHandler 跨用户搜索订单
+func searchOrdersHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orderID, _ := strconv.ParseInt(c.Query("order_id"), 10, 64)
+		userID, _ := strconv.ParseInt(c.Query("user_id"), 10, 64)
+		pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "20"), 10, 32)
+		from, err := parseTimeParam(c.Query("from"), false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to, err := parseTimeParam(c.Query("to"), true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		statuses, err := parseOrderStatuses(c.QueryArray("status"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		resp, err := orderSvc.SearchOrders(authContext(c), &proto.SearchOrdersRequest{
+			OrderId:         orderID,
+			UserId:          userID,
+			Username:        c.Query("username"),
+			ProductName:     c.Query("product_name"),
+			Statuses:        statuses,
+			CreatedFrom:     from,
+			CreatedTo:       to,
+			IncludeArchived: c.Query("include_archived") == "true",
+			PageSize:        int32(pageSize),
+			Cursor:          c.Query("cursor"),
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// orderExportWriter 将订单写为 CSV（每个订单项一行）或 NDJSON（每个订单一行）
+type orderExportWriter struct {
+	rw      gin.ResponseWriter
+	csv     *csv.Writer
+	encoder *json.Encoder
+}
+
+var orderExportHeader = []string{
+	"order_id", "user_id", "status", "total_price", "archived", "created_at", "updated_at",
+	"product_id", "product_name", "quantity", "price",
+}
+
+func newOrderExportWriter(rw gin.ResponseWriter, format string) *orderExportWriter {
+	w := &orderExportWriter{rw: rw}
+	if format == "csv" {
+		// 写入 BOM 方便 Excel 正确识别中文商品名
+		rw.Write([]byte("\xEF\xBB\xBF"))
+		w.csv = csv.NewWriter(rw)
+		w.csv.Write(orderExportHeader)
+	} else {
+		w.encoder = json.NewEncoder(rw)
+	}
+	return w
+}
+
+func (w *orderExportWriter) write(order *proto.Order) error {
+	if w.encoder != nil {
+		return w.encoder.Encode(order)
+	}
+	base := []string{
+		strconv.FormatInt(order.Id, 10),
+		strconv.FormatInt(order.UserId, 10),
+		order.Status.String(),
+		strconv.FormatFloat(order.TotalPrice, 'f', 2, 64),
+		strconv.FormatBool(order.Archived),
+		order.CreatedAt,
+		order.UpdatedAt,
+	}
+	if len(order.Items) == 0 {
+		return w.csv.Write(append(base, "", "", "", ""))
+	}
+	for _, it := range order.Items {
+		row := append(append([]string{}, base...),
+			strconv.FormatInt(it.ProductId, 10),
+			it.ProductName,
+			strconv.FormatInt(int64(it.Quantity), 10),
+			strconv.FormatFloat(it.Price, 'f', 2, 64),
+		)
+		if err := w.csv.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *orderExportWriter) flush() {
+	if w.csv != nil {
+		w.csv.Flush()
+	}
+	w.rw.Flush()
+}
@@ -0,0 +1,138 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// priceScheduleStatusOptions 定时调价状态的查询参数取值
+var priceScheduleStatusOptions = map[string]proto.PriceScheduleStatus{
+	"pending":  proto.PriceScheduleStatus_PRICE_SCHEDULE_PENDING,
+	"active":   proto.PriceScheduleStatus_PRICE_SCHEDULE_ACTIVE,
+	"ended":    proto.PriceScheduleStatus_PRICE_SCHEDULE_ENDED,
+	"canceled": proto.PriceScheduleStatus_PRICE_SCHEDULE_CANCELED,
+}
+
+// schedulePriceChangeHandler 创建定时调价，请求体为 {"sku_id": 0, "price": 79.9, "start_at": "2026-10-23", "end_at": "2026-10-25", "note": "周末促销"}，
+// 时间支持 RFC3339 或日期，日期形式的 end_at 包含当天
+func schedulePriceChangeHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		var body struct {
+			SkuID   int64   `json:"sku_id"`
+			Price   float64 `json:"price" binding:"required"`
+			StartAt string  `json:"start_at" binding:"required"`
+			EndAt   string  `json:"end_at"`
+			Note    string  `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		startAt, err := parseTimeParam(body.StartAt, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		endAt, err := parseTimeParam(body.EndAt, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := productSvc.SchedulePriceChange(authContext(c), &proto.SchedulePriceChangeRequest{
+			ProductId: productID,
+			SkuId:     body.SkuID,
+			Price:     body.Price,
+			StartAt:   startAt,
+			EndAt:     endAt,
+			Note:      body.Note,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// listPriceSchedulesHandler 商品的定时调价，支持 status（可逗号分隔多个）过滤
+func listPriceSchedulesHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		var statuses []proto.PriceScheduleStatus
+		for _, v := range c.QueryArray("status") {
+			for _, name := range strings.Split(v, ",") {
+				st, ok := priceScheduleStatusOptions[strings.TrimSpace(name)]
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status " + name})
+					return
+				}
+				statuses = append(statuses, st)
+			}
+		}
+		resp, err := productSvc.ListPriceSchedules(authContext(c), &proto.ListPriceSchedulesRequest{
+			ProductId: productID,
+			Statuses:  statuses,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// cancelPriceScheduleHandler 取消定时调价，已生效的调价立即恢复原价
+func cancelPriceScheduleHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheduleID, err := strconv.ParseInt(c.Param("schedule_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule id"})
+			return
+		}
+		resp, err := productSvc.CancelPriceSchedule(authContext(c), &proto.CancelPriceScheduleRequest{ScheduleId: scheduleID})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// priceHistoryHandler 价格变动记录，支持 sku_id 过滤
+func priceHistoryHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		skuID, _ := strconv.ParseInt(c.Query("sku_id"), 10, 64)
+		page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+		pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "20"), 10, 32)
+		resp, err := productSvc.GetPriceHistory(authContext(c), &proto.GetPriceHistoryRequest{
+			ProductId: productID,
+			SkuId:     skuID,
+			Page:      int32(page),
+			PageSize:  int32(pageSize),
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,310 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// productRowColumns 商品导入导出的列，导入时按表头名称匹配，列的顺序不限
+var productRowColumns = []string{"external_code", "name", "description", "price", "stock", "main_image", "category_ids", "status"}
+
+// rowReader 逐行读取表格，CSV 和 XLSX 各有一个实现
+type rowReader interface {
+	next() ([]string, error) // 读完时返回 io.EOF
+	close()
+}
+
+type csvRowReader struct {
+	r *csv.Reader
+}
+
+func (r *csvRowReader) next() ([]string, error) {
+	return r.r.Read()
+}
+
+func (r *csvRowReader) close() {}
+
+type xlsxRowReader struct {
+	file *excelize.File
+	rows *excelize.Rows
+}
+
+func (r *xlsxRowReader) next() ([]string, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return r.rows.Columns()
+}
+
+// close 释放 excelize 解压大文件时使用的临时文件
+func (r *xlsxRowReader) close() {
+	r.rows.Close()
+	r.file.Close()
+}
+
+// newRowReader 按扩展名选择读取方式，XLSX 读取第一个工作表
+func newRowReader(file io.Reader, filename string) (rowReader, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		r := csv.NewReader(file)
+		r.FieldsPerRecord = -1
+		return &csvRowReader{r: r}, nil
+	case ".xlsx":
+		f, err := excelize.OpenReader(file)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := f.Rows(f.GetSheetName(0))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &xlsxRowReader{file: f, rows: rows}, nil
+	default:
+		return nil, errors.New("only .csv and .xlsx files are supported")
+	}
+}
+
+// importProductsHandler 上传 CSV/XLSX 批量导入商品，dry_run=true 时只校验不写入
+func importProductsHandler(productSvc *service.ProductService, maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		if fileHeader.Size > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be smaller than %d bytes", maxSize)})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+			return
+		}
+		defer file.Close()
+		reader, err := newRowReader(file, fileHeader.Filename)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer reader.close()
+
+		// 第一行为表头
+		header, err := reader.next()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read header"})
+			return
+		}
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			// 去掉 Excel 导出的 CSV 开头的 BOM
+			name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\xEF\xBB\xBF")))
+			columns[name] = i
+		}
+		if _, ok := columns["external_code"]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "external_code column is required"})
+			return
+		}
+
+		stream, err := productSvc.ImportProducts(authContext(c))
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		options := &proto.ImportProductsRequest{Payload: &proto.ImportProductsRequest_Options{
+			Options: &proto.ImportProductsOptions{DryRun: c.Query("dry_run") == "true"},
+		}}
+		// 服务端提前结束时 Send 返回 io.EOF，实际错误由 CloseAndRecv 返回
+		sendErr := stream.Send(options)
+		for line := int32(2); sendErr == nil; line++ {
+			cells, err := reader.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				stream.CloseSend()
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read line %d: %v", line, err)})
+				return
+			}
+			row := newProductRow(columns, cells, line)
+			if row == nil {
+				continue
+			}
+			sendErr = stream.Send(&proto.ImportProductsRequest{Payload: &proto.ImportProductsRequest_Row{Row: row}})
+		}
+		resp, err := stream.CloseAndRecv()
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// newProductRow 按表头将单元格转为导入行，空行返回 nil
+func newProductRow(columns map[string]int, cells []string, line int32) *proto.ProductRow {
+	cell := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(cells) {
+			return ""
+		}
+		return strings.TrimSpace(cells[i])
+	}
+	empty := true
+	for _, v := range cells {
+		if strings.TrimSpace(v) != "" {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return nil
+	}
+	return &proto.ProductRow{
+		Line:         line,
+		ExternalCode: cell("external_code"),
+		Name:         cell("name"),
+		Description:  cell("description"),
+		Price:        cell("price"),
+		Stock:        cell("stock"),
+		MainImage:    cell("main_image"),
+		CategoryIds:  cell("category_ids"),
+		Status:       cell("status"),
+	}
+}
+
+func productRowCells(row *proto.ProductRow) []string {
+	return []string{row.ExternalCode, row.Name, row.Description, row.Price, row.Stock, row.MainImage, row.CategoryIds, row.Status}
+}
+
+// exportProductsHandler 导出商品，format=csv（默认，流式输出）或 xlsx
+func exportProductsHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "csv")
+		if format != "csv" && format != "xlsx" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+			return
+		}
+		categoryID, _ := strconv.ParseInt(c.Query("category_id"), 10, 64)
+		stream, err := productSvc.ExportProducts(authContext(c), &proto.ExportProductsRequest{
+			CategoryId: categoryID,
+			Keyword:    c.Query("keyword"),
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		// 先读取第一条，确保鉴权等错误能以正常的 HTTP 错误返回
+		first, err := stream.Recv()
+		if err != nil && err != io.EOF {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		filename := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102150405"), format)
+
+		if format == "xlsx" {
+			writeProductsXLSX(c, filename, first, stream)
+			return
+		}
+
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		// 写入 BOM 方便 Excel 正确识别中文商品名
+		c.Writer.Write([]byte("\xEF\xBB\xBF"))
+		w := csv.NewWriter(c.Writer)
+		w.Write(productRowColumns)
+		rows := 0
+		for row := first; row != nil; {
+			if err := w.Write(productRowCells(row)); err != nil {
+				log.Printf("Export products write failed: %v", err)
+				return
+			}
+			if rows++; rows%exportFlushRows == 0 {
+				w.Flush()
+				c.Writer.Flush()
+			}
+			row, err = stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				// 响应头已发送，只能中断输出
+				log.Printf("Export products stream failed: %v", err)
+				break
+			}
+		}
+		w.Flush()
+		c.Writer.Flush()
+	}
+}
+
+// writeProductsXLSX XLSX 需要完整生成后才能输出，读取完所有行再写入响应
+func writeProductsXLSX(c *gin.Context, filename string, first *proto.ProductRow, stream proto.ProductService_ExportProductsClient) {
+	f := excelize.NewFile()
+	defer f.Close()
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create xlsx"})
+		return
+	}
+	toValues := func(cells []string) []interface{} {
+		values := make([]interface{}, len(cells))
+		for i, v := range cells {
+			values[i] = v
+		}
+		return values
+	}
+	if err := sw.SetRow("A1", toValues(productRowColumns)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create xlsx"})
+		return
+	}
+	line := 1
+	for row := first; row != nil; {
+		line++
+		cellName, _ := excelize.CoordinatesToCellName(1, line)
+		if err := sw.SetRow(cellName, toValues(productRowCells(row))); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create xlsx"})
+			return
+		}
+		row, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create xlsx"})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Status(http.StatusOK)
+	if err := f.Write(c.Writer); err != nil {
+		log.Printf("Export products write failed: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// relatedProductsHandler 经常与该商品一起购买的商品，limit 默认 10
+func relatedProductsHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		limit, _ := strconv.ParseInt(c.Query("limit"), 10, 32)
+		resp, err := orderSvc.GetRelatedProducts(authContext(c), &proto.GetRelatedProductsRequest{
+			ProductId: productID,
+			Limit:     int32(limit),
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// recommendationsHandler 当前用户的推荐商品，管理员可以通过 user_id 查询其他用户
+func recommendationsHandler(orderSvc *service.OrderService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := strconv.ParseInt(c.Query("user_id"), 10, 64)
+		limit, _ := strconv.ParseInt(c.Query("limit"), 10, 32)
+		resp, err := orderSvc.GetRecommendationsForUser(authContext(c), &proto.GetRecommendationsForUserRequest{
+			UserId: userID,
+			Limit:  int32(limit),
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
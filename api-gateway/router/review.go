@@ -0,0 +1,138 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listReviewsHandler 商品评价列表，携带客服/管理员 token 时可通过 status 查看待审核的评价
+func listReviewsHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+		pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 32)
+		rating, _ := strconv.ParseInt(c.Query("rating"), 10, 32)
+		sortBy := proto.ReviewSortBy_REVIEW_SORT_NEWEST
+		if c.Query("sort") == "helpful" {
+			sortBy = proto.ReviewSortBy_REVIEW_SORT_HELPFUL
+		}
+		var statuses []proto.ReviewStatus
+		for _, v := range c.QueryArray("status") {
+			for _, name := range strings.Split(v, ",") {
+				n, ok := proto.ReviewStatus_value["REVIEW_"+strings.ToUpper(strings.TrimSpace(name))]
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status " + name})
+					return
+				}
+				statuses = append(statuses, proto.ReviewStatus(n))
+			}
+		}
+
+		resp, err := productSvc.ListReviews(authContext(c), &proto.ListReviewsRequest{
+			ProductId: productID,
+			Page:      int32(page),
+			PageSize:  int32(pageSize),
+			SortBy:    sortBy,
+			Rating:    int32(rating),
+			Statuses:  statuses,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// createReviewHandler 发表评价，请求体为 {"order_id": 1, "order_item_id": 2, "rating": 5, "content": "..."}
+func createReviewHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		var body struct {
+			OrderID     int64  `json:"order_id" binding:"required"`
+			OrderItemID int64  `json:"order_item_id" binding:"required"`
+			Rating      int32  `json:"rating" binding:"required"`
+			Content     string `json:"content"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := productSvc.CreateReview(authContext(c), &proto.CreateReviewRequest{
+			ProductId:   productID,
+			OrderId:     body.OrderID,
+			OrderItemId: body.OrderItemID,
+			Rating:      body.Rating,
+			Content:     body.Content,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// moderateReviewHandler 审核评价，请求体为 {"status": "approved" | "rejected", "note": "..."}
+func moderateReviewHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review id"})
+			return
+		}
+		var body struct {
+			Status string `json:"status" binding:"required"`
+			Note   string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		st, ok := proto.ReviewStatus_value["REVIEW_"+strings.ToUpper(body.Status)]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		resp, err := productSvc.ModerateReview(authContext(c), &proto.ModerateReviewRequest{
+			ReviewId: reviewID,
+			Status:   proto.ReviewStatus(st),
+			Note:     body.Note,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// voteReviewHelpfulHandler 为评价投“有帮助”
+func voteReviewHelpfulHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review id"})
+			return
+		}
+		resp, err := productSvc.VoteReviewHelpful(authContext(c), &proto.VoteReviewHelpfulRequest{ReviewId: reviewID})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -1,19 +1,39 @@
 package router
 
 import (
+	"api-gateway/config"
 	"api-gateway/middleware"
 	"api-gateway/proto"
 	"api-gateway/service"
+	"api-gateway/storage"
 	"log"
 
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc/metadata"
 )
 
-func SetupRouter(userSvc *service.UserService, productSvc *service.ProductService) *gin.Engine {
+// productSortOptions 商品列表 sort 参数与排序方式的对应关系
+var productSortOptions = map[string]proto.ProductSortBy{
+	"":             proto.ProductSortBy_PRODUCT_SORT_DEFAULT,
+	"price_asc":    proto.ProductSortBy_PRODUCT_SORT_PRICE_ASC,
+	"price_desc":   proto.ProductSortBy_PRODUCT_SORT_PRICE_DESC,
+	"newest":       proto.ProductSortBy_PRODUCT_SORT_NEWEST,
+	"best_selling": proto.ProductSortBy_PRODUCT_SORT_BEST_SELLING,
+	"rating":       proto.ProductSortBy_PRODUCT_SORT_RATING,
+}
+
+// productStatusOptions 商品状态参数的取值
+var productStatusOptions = map[string]proto.ProductStatus{
+	"draft":    proto.ProductStatus_PRODUCT_STATUS_DRAFT,
+	"active":   proto.ProductStatus_PRODUCT_STATUS_ACTIVE,
+	"archived": proto.ProductStatus_PRODUCT_STATUS_ARCHIVED,
+}
+
+func SetupRouter(userSvc *service.UserService, productSvc *service.ProductService, orderSvc *service.OrderService, store storage.Storage) *gin.Engine {
 	r := gin.Default()
 
 	// 健康检查
@@ -26,7 +46,7 @@ func SetupRouter(userSvc *service.UserService, productSvc *service.ProductServic
 	// API 路由组
 	v1 := r.Group("/api/v1")
 	{
-		// 用户服务路由 (注册和登录不需要认证)
+		// 用户服务路由 (注册、登录、刷新和退出登录不需要认证)
 		userRoutes := v1.Group("/users")
 		{
 			userRoutes.POST("/register", func(c *gin.Context) {
@@ -56,6 +76,36 @@ func SetupRouter(userSvc *service.UserService, productSvc *service.ProductServic
 				}
 				c.JSON(http.StatusOK, resp)
 			})
+
+			// 用 refresh token 换取新的 token，请求体为 {"refresh_token": "..."}
+			userRoutes.POST("/refresh", func(c *gin.Context) {
+				var req proto.RefreshTokenRequest
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				resp, err := userSvc.RefreshToken(c.Request.Context(), &req)
+				if err != nil {
+					c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, resp)
+			})
+
+			// 退出登录，吊销携带的 token 和请求体中的 refresh token；token 已过期时只凭 refresh token 也可以退出
+			userRoutes.POST("/logout", func(c *gin.Context) {
+				var req proto.LogoutRequest
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				resp, err := userSvc.Logout(authContext(c), &req)
+				if err != nil {
+					c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, resp)
+			})
 		}
 
 		// 公开的商品服务路由
@@ -65,19 +115,92 @@ func SetupRouter(userSvc *service.UserService, productSvc *service.ProductServic
 			productRoutes.GET("", func(c *gin.Context) {
 				page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
 				pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 32)
+				categoryID, _ := strconv.ParseInt(c.Query("category_id"), 10, 64)
+				sortBy, ok := productSortOptions[c.Query("sort")]
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort option"})
+					return
+				}
+				// status 可逗号分隔多个，仅管理员可用
+				var statuses []proto.ProductStatus
+				for _, v := range c.QueryArray("status") {
+					for _, name := range strings.Split(v, ",") {
+						st, ok := productStatusOptions[strings.TrimSpace(name)]
+						if !ok {
+							c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status " + name})
+							return
+						}
+						statuses = append(statuses, st)
+					}
+				}
 
-				// 这里不需要 Authorization 头部，但为了传递可能的 Trace ID 等，可以传递 Context
-				resp, err := productSvc.ListProducts(c.Request.Context(), &proto.ListProductsRequest{
-					Page:     int32(page),
-					PageSize: int32(pageSize),
+				// 不要求登录；携带 token 时透传，管理员可以查询未上架的商品
+				resp, err := productSvc.ListProducts(authContext(c), &proto.ListProductsRequest{
+					Page:       int32(page),
+					PageSize:   int32(pageSize),
+					Keyword:    c.Query("keyword"),
+					CategoryId: categoryID,
+					SortBy:     sortBy,
+					Statuses:   statuses,
 				})
 				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, resp)
+			})
+
+			// 搜索商品
+			productRoutes.GET("/search", func(c *gin.Context) {
+				page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+				pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 32)
+				minPrice, _ := strconv.ParseFloat(c.Query("min_price"), 64)
+				maxPrice, _ := strconv.ParseFloat(c.Query("max_price"), 64)
+				categoryID, _ := strconv.ParseInt(c.Query("category_id"), 10, 64)
+
+				resp, err := productSvc.SearchProducts(c.Request.Context(), &proto.SearchProductsRequest{
+					Keyword:    c.Query("q"),
+					MinPrice:   minPrice,
+					MaxPrice:   maxPrice,
+					CategoryId: categoryID,
+					Page:       int32(page),
+					PageSize:   int32(pageSize),
+				})
+				if err != nil {
+					c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
 					return
 				}
 				c.JSON(http.StatusOK, resp)
 			})
 
+			// 批量获取商品，ids=1,2,3
+			productRoutes.GET("/batch", func(c *gin.Context) {
+				var ids []int64
+				for _, v := range strings.Split(c.Query("ids"), ",") {
+					if v = strings.TrimSpace(v); v == "" {
+						continue
+					}
+					id, err := strconv.ParseInt(v, 10, 64)
+					if err != nil {
+						c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+						return
+					}
+					ids = append(ids, id)
+				}
+				resp, err := productSvc.BatchGetProducts(authContext(c), &proto.BatchGetProductsRequest{ProductIds: ids})
+				if err != nil {
+					c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, resp)
+			})
+
+			// 商品评价列表
+			productRoutes.GET("/:id/reviews", listReviewsHandler(productSvc))
+
+			// 经常一起购买的商品
+			productRoutes.GET("/:id/related", relatedProductsHandler(orderSvc))
+
 			// 获取商品详情
 			productRoutes.GET("/:id", func(c *gin.Context) {
 				productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -86,16 +209,30 @@ func SetupRouter(userSvc *service.UserService, productSvc *service.ProductServic
 					return
 				}
 
-				// 这里不需要 Authorization 头部，但为了传递可能的 Trace ID 等，可以传递 Context
-				resp, err := productSvc.GetProduct(c.Request.Context(), &proto.GetProductRequest{ProductId: productID})
+				// 不要求登录；携带 token 时透传，管理员可以查看未上架的商品
+				resp, err := productSvc.GetProduct(authContext(c), &proto.GetProductRequest{ProductId: productID})
 				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
 					return
 				}
 				c.JSON(http.StatusOK, resp)
 			})
 		}
 
+		// 公开的商品分类路由
+		v1.GET("/categories", func(c *gin.Context) {
+			parentID, _ := strconv.ParseInt(c.Query("parent_id"), 10, 64)
+			resp, err := productSvc.ListCategories(c.Request.Context(), &proto.ListCategoriesRequest{ParentId: parentID})
+			if err != nil {
+				c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, resp)
+		})
+
+		// 进行中和即将开始的秒杀活动
+		v1.GET("/flash-sales", listFlashSalesHandler(orderSvc))
+
 		// 需要认证的路由
 		authRoutes := v1.Group("")
 		authRoutes.Use(middleware.AuthMiddleware())
@@ -228,6 +365,92 @@ func SetupRouter(userSvc *service.UserService, productSvc *service.ProductServic
 				authProductRoutes.POST("", nil)       // TODO: 创建商品
 				authProductRoutes.PUT("/:id", nil)    // TODO: 更新商品
 				authProductRoutes.DELETE("/:id", nil) // TODO: 删除商品
+
+				// 管理员查看商品缓存命中统计
+				authProductRoutes.GET("/cache/stats", func(c *gin.Context) {
+					resp, err := productSvc.GetCacheStats(authContext(c), &proto.GetCacheStatsRequest{})
+					if err != nil {
+						c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+						return
+					}
+					c.JSON(http.StatusOK, resp)
+				})
+			}
+
+			// 管理员维护商品图集
+			productImageRoutes := authRoutes.Group("/products/:id/images")
+			{
+				productImageRoutes.POST("", uploadProductImageHandler(productSvc, store, config.DefaultConfig.Upload.MaxImageSize))
+				productImageRoutes.PUT("/order", reorderProductImagesHandler(productSvc))
+				productImageRoutes.DELETE("/:image_id", deleteProductImageHandler(productSvc, store))
+			}
+
+			// 商品上架、下架（管理员），请求体为 {"status": "active"}
+			authRoutes.PUT("/products/:id/status", func(c *gin.Context) {
+				productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+					return
+				}
+				var body struct {
+					Status string `json:"status" binding:"required"`
+				}
+				if err := c.ShouldBindJSON(&body); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				st, ok := productStatusOptions[body.Status]
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status " + body.Status})
+					return
+				}
+				resp, err := productSvc.SetProductStatus(authContext(c), &proto.SetProductStatusRequest{ProductId: productID, Status: st})
+				if err != nil {
+					c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, resp)
+			})
+
+			// 商品批量导入导出（管理员）
+			authRoutes.POST("/products/import", importProductsHandler(productSvc, config.DefaultConfig.Upload.MaxImportSize))
+			authRoutes.GET("/products/export", exportProductsHandler(productSvc))
+
+			// 各仓库库存、库存调整和流水（管理员）
+			stockRoutes := authRoutes.Group("/products/:id/stock")
+			{
+				stockRoutes.GET("", warehouseStockHandler(productSvc))
+				stockRoutes.POST("/adjustments", adjustStockHandler(productSvc))
+				stockRoutes.GET("/history", stockHistoryHandler(productSvc))
+				stockRoutes.PUT("/threshold", setLowStockThresholdHandler(productSvc))
+			}
+
+			// 低库存和售罄预警（管理员）
+			authRoutes.GET("/stock-alerts", stockAlertsHandler(userSvc))
+
+			// 定时调价和价格变动记录（管理员）
+			priceRoutes := authRoutes.Group("/products/:id/prices")
+			{
+				priceRoutes.GET("/history", priceHistoryHandler(productSvc))
+				priceRoutes.GET("/schedules", listPriceSchedulesHandler(productSvc))
+				priceRoutes.POST("/schedules", schedulePriceChangeHandler(productSvc))
+				priceRoutes.DELETE("/schedules/:schedule_id", cancelPriceScheduleHandler(productSvc))
+			}
+
+			// 仓库管理（管理员）
+			warehouseRoutes := authRoutes.Group("/warehouses")
+			{
+				warehouseRoutes.POST("", createWarehouseHandler(productSvc))
+				warehouseRoutes.GET("", listWarehousesHandler(productSvc))
+				warehouseRoutes.PUT("/:id", updateWarehouseHandler(productSvc))
+			}
+
+			// 商品评价：发表、审核和投票
+			authRoutes.POST("/products/:id/reviews", createReviewHandler(productSvc))
+			reviewRoutes := authRoutes.Group("/reviews")
+			{
+				reviewRoutes.PUT("/:id/moderation", moderateReviewHandler(productSvc))
+				reviewRoutes.POST("/:id/helpful", voteReviewHelpfulHandler(productSvc))
 			}
 
 			// 订单服务路由
@@ -236,6 +459,42 @@ func SetupRouter(userSvc *service.UserService, productSvc *service.ProductServic
 				orderRoutes.POST("", nil)    // TODO: 创建订单
 				orderRoutes.GET("/:id", nil) // TODO: 获取订单详情
 				orderRoutes.GET("", nil)     // TODO: 获取订单列表
+
+				// 财务导出订单（CSV / NDJSON 流式输出）
+				orderRoutes.GET("/export", exportOrdersHandler(orderSvc))
+
+				// 客服/管理员搜索订单
+				orderRoutes.GET("/search", searchOrdersHandler(orderSvc))
+			}
+
+			// 根据购买记录推荐商品
+			authRoutes.GET("/recommendations", recommendationsHandler(orderSvc))
+
+			// 收藏夹、到货和降价提醒订阅、站内通知
+			wishlistRoutes := authRoutes.Group("/wishlist")
+			{
+				wishlistRoutes.GET("", listWishlistHandler(userSvc))
+				wishlistRoutes.POST("", addWishlistItemHandler(userSvc))
+				wishlistRoutes.DELETE("/:product_id", removeWishlistItemHandler(userSvc))
+			}
+			subscriptionRoutes := authRoutes.Group("/subscriptions")
+			{
+				subscriptionRoutes.GET("", listSubscriptionsHandler(userSvc))
+				subscriptionRoutes.POST("", subscribeProductHandler(userSvc))
+				subscriptionRoutes.DELETE("/:product_id", unsubscribeProductHandler(userSvc))
+			}
+			notificationRoutes := authRoutes.Group("/notifications")
+			{
+				notificationRoutes.GET("", listNotificationsHandler(userSvc))
+				notificationRoutes.PUT("/read", markNotificationsReadHandler(userSvc))
+			}
+
+			// 秒杀：创建活动（管理员）、抢购和轮询抢购结果
+			flashSaleRoutes := authRoutes.Group("/flash-sales")
+			{
+				flashSaleRoutes.POST("", createFlashSaleHandler(orderSvc))
+				flashSaleRoutes.POST("/:id/purchase", purchaseFlashSaleHandler(orderSvc))
+				flashSaleRoutes.GET("/:id/result", flashSaleResultHandler(orderSvc))
 			}
 		}
 	}
@@ -0,0 +1,171 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stockReasonOptions 库存变动原因的查询参数取值
+var stockReasonOptions = map[string]proto.StockReason{
+	"adjustment":   proto.StockReason_STOCK_REASON_ADJUSTMENT,
+	"initial":      proto.StockReason_STOCK_REASON_INITIAL,
+	"reservation":  proto.StockReason_STOCK_REASON_RESERVATION,
+	"sale":         proto.StockReason_STOCK_REASON_SALE,
+	"cancellation": proto.StockReason_STOCK_REASON_CANCELLATION,
+	"return":       proto.StockReason_STOCK_REASON_RETURN,
+}
+
+// stockAlertTypeOptions 库存预警类型的查询参数取值
+var stockAlertTypeOptions = map[string]proto.StockAlertType{
+	"low":          proto.StockAlertType_STOCK_ALERT_LOW,
+	"out_of_stock": proto.StockAlertType_STOCK_ALERT_OUT_OF_STOCK,
+}
+
+// adjustStockHandler 调整库存，请求体为 {"warehouse_id": 1, "sku_id": 0, "delta": -3, "reason": "adjustment", "reference": "", "note": "盘点"}，
+// warehouse_id 为 0 时使用默认仓库
+func adjustStockHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		var body struct {
+			WarehouseID int64  `json:"warehouse_id"`
+			SkuID       int64  `json:"sku_id"`
+			Delta       int32  `json:"delta" binding:"required"`
+			Reason      string `json:"reason"`
+			Reference   string `json:"reference"`
+			Note        string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reason := proto.StockReason_STOCK_REASON_ADJUSTMENT
+		if body.Reason != "" {
+			r, ok := stockReasonOptions[body.Reason]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reason " + body.Reason})
+				return
+			}
+			reason = r
+		}
+		resp, err := productSvc.AdjustStock(authContext(c), &proto.AdjustStockRequest{
+			ProductId:   productID,
+			SkuId:       body.SkuID,
+			Delta:       body.Delta,
+			Reason:      reason,
+			Reference:   body.Reference,
+			Note:        body.Note,
+			WarehouseId: body.WarehouseID,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// stockHistoryHandler 库存流水，支持 sku_id、warehouse_id 和 reason（可逗号分隔多个）过滤
+func stockHistoryHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		skuID, _ := strconv.ParseInt(c.Query("sku_id"), 10, 64)
+		warehouseID, _ := strconv.ParseInt(c.Query("warehouse_id"), 10, 64)
+		page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+		pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "20"), 10, 32)
+		var reasons []proto.StockReason
+		for _, v := range c.QueryArray("reason") {
+			for _, name := range strings.Split(v, ",") {
+				r, ok := stockReasonOptions[strings.TrimSpace(name)]
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reason " + name})
+					return
+				}
+				reasons = append(reasons, r)
+			}
+		}
+		resp, err := productSvc.GetStockHistory(authContext(c), &proto.GetStockHistoryRequest{
+			ProductId:   productID,
+			SkuId:       skuID,
+			Reasons:     reasons,
+			Page:        int32(page),
+			PageSize:    int32(pageSize),
+			WarehouseId: warehouseID,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// setLowStockThresholdHandler 设置低库存预警阈值，请求体为 {"threshold": 10}，0 表示只预警售罄
+func setLowStockThresholdHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		var body struct {
+			Threshold *int32 `json:"threshold" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := productSvc.SetLowStockThreshold(authContext(c), &proto.SetLowStockThresholdRequest{
+			ProductId: productID,
+			Threshold: *body.Threshold,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// stockAlertsHandler 库存预警，支持 product_id 和 type（low、out_of_stock，可逗号分隔多个）过滤
+func stockAlertsHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, _ := strconv.ParseInt(c.Query("product_id"), 10, 64)
+		page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+		pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "20"), 10, 32)
+		var types []proto.StockAlertType
+		for _, v := range c.QueryArray("type") {
+			for _, name := range strings.Split(v, ",") {
+				t, ok := stockAlertTypeOptions[strings.TrimSpace(name)]
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type " + name})
+					return
+				}
+				types = append(types, t)
+			}
+		}
+		resp, err := userSvc.ListStockAlerts(authContext(c), &proto.ListStockAlertsRequest{
+			ProductId: productID,
+			Types:     types,
+			Page:      int32(page),
+			PageSize:  int32(pageSize),
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// warehouseBody 创建和修改仓库的请求体
+type warehouseBody struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Priority  int32   `json:"priority"`
+	Active    *bool   `json:"active"`
+}
+
+// createWarehouseHandler 创建仓库
+func createWarehouseHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body warehouseBody
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := productSvc.CreateWarehouse(authContext(c), &proto.CreateWarehouseRequest{
+			Code:      body.Code,
+			Name:      body.Name,
+			Latitude:  body.Latitude,
+			Longitude: body.Longitude,
+			Priority:  body.Priority,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// updateWarehouseHandler 修改仓库，未传 active 时视为启用
+func updateWarehouseHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		warehouseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse id"})
+			return
+		}
+		var body warehouseBody
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		active := body.Active == nil || *body.Active
+		resp, err := productSvc.UpdateWarehouse(authContext(c), &proto.UpdateWarehouseRequest{
+			WarehouseId: warehouseID,
+			Name:        body.Name,
+			Latitude:    body.Latitude,
+			Longitude:   body.Longitude,
+			Priority:    body.Priority,
+			Active:      active,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// listWarehousesHandler 仓库列表
+func listWarehousesHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp, err := productSvc.ListWarehouses(authContext(c), &proto.ListWarehousesRequest{})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// warehouseStockHandler 商品在各仓库的库存
+func warehouseStockHandler(productSvc *service.ProductService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		resp, err := productSvc.GetWarehouseStock(authContext(c), &proto.GetWarehouseStockRequest{ProductId: productID})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
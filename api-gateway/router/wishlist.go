@@ -0,0 +1,180 @@
+package router
+
+import (
+	"api-gateway/proto"
+	"api-gateway/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// subscriptionTypeOptions 订阅类型的取值
+var subscriptionTypeOptions = map[string]proto.SubscriptionType{
+	"back_in_stock": proto.SubscriptionType_SUBSCRIPTION_BACK_IN_STOCK,
+	"price_drop":    proto.SubscriptionType_SUBSCRIPTION_PRICE_DROP,
+}
+
+// listWishlistHandler 当前用户的收藏夹
+func listWishlistHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp, err := userSvc.ListWishlist(authContext(c), &proto.ListWishlistRequest{})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// addWishlistItemHandler 收藏商品，请求体为 {"product_id": 1}
+func addWishlistItemHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			ProductID int64 `json:"product_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp, err := userSvc.AddWishlistItem(authContext(c), &proto.AddWishlistItemRequest{ProductId: body.ProductID})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// removeWishlistItemHandler 取消收藏
+func removeWishlistItemHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("product_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		resp, err := userSvc.RemoveWishlistItem(authContext(c), &proto.RemoveWishlistItemRequest{ProductId: productID})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// listSubscriptionsHandler 当前用户的到货和降价提醒订阅
+func listSubscriptionsHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp, err := userSvc.ListSubscriptions(authContext(c), &proto.ListSubscriptionsRequest{})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// subscribeProductHandler 订阅商品提醒，请求体为 {"product_id": 1, "sku_id": 2, "type": "back_in_stock"}，
+// type 也可以是 price_drop，不传 sku_id 时订阅整个商品
+func subscribeProductHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			ProductID int64  `json:"product_id" binding:"required"`
+			SkuID     int64  `json:"sku_id"`
+			Type      string `json:"type" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		subType, ok := subscriptionTypeOptions[body.Type]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type " + body.Type})
+			return
+		}
+		resp, err := userSvc.SubscribeProduct(authContext(c), &proto.SubscribeProductRequest{
+			ProductId: body.ProductID,
+			SkuId:     body.SkuID,
+			Type:      subType,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// unsubscribeProductHandler 取消订阅，type 查询参数为 back_in_stock 或 price_drop，sku_id 查询参数为订阅的规格
+func unsubscribeProductHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.ParseInt(c.Param("product_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			return
+		}
+		subType, ok := subscriptionTypeOptions[c.Query("type")]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid type " + c.Query("type")})
+			return
+		}
+		var skuID int64
+		if v := c.Query("sku_id"); v != "" {
+			if skuID, err = strconv.ParseInt(v, 10, 64); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sku id"})
+				return
+			}
+		}
+		resp, err := userSvc.UnsubscribeProduct(authContext(c), &proto.UnsubscribeProductRequest{
+			ProductId: productID,
+			SkuId:     skuID,
+			Type:      subType,
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// listNotificationsHandler 当前用户的站内通知，unread_only=true 时只返回未读通知
+func listNotificationsHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		unreadOnly, _ := strconv.ParseBool(c.Query("unread_only"))
+		page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+		pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "20"), 10, 32)
+		resp, err := userSvc.ListNotifications(authContext(c), &proto.ListNotificationsRequest{
+			UnreadOnly: unreadOnly,
+			Page:       int32(page),
+			PageSize:   int32(pageSize),
+		})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// markNotificationsReadHandler 标记通知为已读，请求体为 {"ids": [1, 2]}，不传 ids 时标记全部
+func markNotificationsReadHandler(userSvc *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			IDs []int64 `json:"ids"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		resp, err := userSvc.MarkNotificationsRead(authContext(c), &proto.MarkNotificationsReadRequest{NotificationIds: body.IDs})
+		if err != nil {
+			c.JSON(httpStatusFromError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -46,3 +46,43 @@ func (s *OrderService) GetOrder(ctx context.Context, req *proto.GetOrderRequest)
 func (s *OrderService) ListOrders(ctx context.Context, req *proto.ListOrdersRequest) (*proto.ListOrdersResponse, error) {
 	return s.client.ListOrders(ctx, req)
 }
+
+// ExportOrders 流式导出订单
+func (s *OrderService) ExportOrders(ctx context.Context, req *proto.ExportOrdersRequest) (proto.OrderService_ExportOrdersClient, error) {
+	return s.client.ExportOrders(ctx, req)
+}
+
+// SearchOrders 客服/管理员跨用户搜索订单
+func (s *OrderService) SearchOrders(ctx context.Context, req *proto.SearchOrdersRequest) (*proto.SearchOrdersResponse, error) {
+	return s.client.SearchOrders(ctx, req)
+}
+
+// CreateFlashSale 创建秒杀活动
+func (s *OrderService) CreateFlashSale(ctx context.Context, req *proto.CreateFlashSaleRequest) (*proto.CreateFlashSaleResponse, error) {
+	return s.client.CreateFlashSale(ctx, req)
+}
+
+// ListFlashSales 未结束的秒杀活动
+func (s *OrderService) ListFlashSales(ctx context.Context, req *proto.ListFlashSalesRequest) (*proto.ListFlashSalesResponse, error) {
+	return s.client.ListFlashSales(ctx, req)
+}
+
+// PurchaseFlashSale 秒杀抢购
+func (s *OrderService) PurchaseFlashSale(ctx context.Context, req *proto.PurchaseFlashSaleRequest) (*proto.PurchaseFlashSaleResponse, error) {
+	return s.client.PurchaseFlashSale(ctx, req)
+}
+
+// GetFlashSaleResult 查询抢购结果
+func (s *OrderService) GetFlashSaleResult(ctx context.Context, req *proto.GetFlashSaleResultRequest) (*proto.GetFlashSaleResultResponse, error) {
+	return s.client.GetFlashSaleResult(ctx, req)
+}
+
+// GetRelatedProducts 经常一起购买的商品
+func (s *OrderService) GetRelatedProducts(ctx context.Context, req *proto.GetRelatedProductsRequest) (*proto.GetRelatedProductsResponse, error) {
+	return s.client.GetRelatedProducts(ctx, req)
+}
+
+// GetRecommendationsForUser 用户的推荐商品
+func (s *OrderService) GetRecommendationsForUser(ctx context.Context, req *proto.GetRecommendationsForUserRequest) (*proto.GetRecommendationsForUserResponse, error) {
+	return s.client.GetRecommendationsForUser(ctx, req)
+}
@@ -39,6 +39,11 @@ func (s *ProductService) GetProduct(ctx context.Context, req *proto.GetProductRe
 	return s.client.GetProduct(ctx, req)
 }
 
+// BatchGetProducts 批量获取商品
+func (s *ProductService) BatchGetProducts(ctx context.Context, req *proto.BatchGetProductsRequest) (*proto.BatchGetProductsResponse, error) {
+	return s.client.BatchGetProducts(ctx, req)
+}
+
 func (s *ProductService) ListProducts(ctx context.Context, req *proto.ListProductsRequest) (*proto.ListProductsResponse, error) {
 	return s.client.ListProducts(ctx, req)
 }
@@ -54,3 +59,123 @@ func (s *ProductService) UpdateProduct(ctx context.Context, req *proto.UpdatePro
 func (s *ProductService) DeleteProduct(ctx context.Context, req *proto.DeleteProductRequest) (*proto.DeleteProductResponse, error) {
 	return s.client.DeleteProduct(ctx, req)
 }
+
+// ListCategories 获取分类树
+func (s *ProductService) ListCategories(ctx context.Context, req *proto.ListCategoriesRequest) (*proto.ListCategoriesResponse, error) {
+	return s.client.ListCategories(ctx, req)
+}
+
+// SearchProducts 全文搜索商品
+func (s *ProductService) SearchProducts(ctx context.Context, req *proto.SearchProductsRequest) (*proto.SearchProductsResponse, error) {
+	return s.client.SearchProducts(ctx, req)
+}
+
+// GetCacheStats 商品缓存命中统计
+func (s *ProductService) GetCacheStats(ctx context.Context, req *proto.GetCacheStatsRequest) (*proto.GetCacheStatsResponse, error) {
+	return s.client.GetCacheStats(ctx, req)
+}
+
+// AddProductImage 登记已上传的商品图片
+func (s *ProductService) AddProductImage(ctx context.Context, req *proto.AddProductImageRequest) (*proto.AddProductImageResponse, error) {
+	return s.client.AddProductImage(ctx, req)
+}
+
+// DeleteProductImage 删除商品图片记录
+func (s *ProductService) DeleteProductImage(ctx context.Context, req *proto.DeleteProductImageRequest) (*proto.DeleteProductImageResponse, error) {
+	return s.client.DeleteProductImage(ctx, req)
+}
+
+// ReorderProductImages 调整商品图片顺序
+func (s *ProductService) ReorderProductImages(ctx context.Context, req *proto.ReorderProductImagesRequest) (*proto.ReorderProductImagesResponse, error) {
+	return s.client.ReorderProductImages(ctx, req)
+}
+
+// CreateReview 发表商品评价
+func (s *ProductService) CreateReview(ctx context.Context, req *proto.CreateReviewRequest) (*proto.CreateReviewResponse, error) {
+	return s.client.CreateReview(ctx, req)
+}
+
+// ListReviews 商品评价列表
+func (s *ProductService) ListReviews(ctx context.Context, req *proto.ListReviewsRequest) (*proto.ListReviewsResponse, error) {
+	return s.client.ListReviews(ctx, req)
+}
+
+// ModerateReview 审核评价
+func (s *ProductService) ModerateReview(ctx context.Context, req *proto.ModerateReviewRequest) (*proto.ModerateReviewResponse, error) {
+	return s.client.ModerateReview(ctx, req)
+}
+
+// VoteReviewHelpful 评价“有帮助”投票
+func (s *ProductService) VoteReviewHelpful(ctx context.Context, req *proto.VoteReviewHelpfulRequest) (*proto.VoteReviewHelpfulResponse, error) {
+	return s.client.VoteReviewHelpful(ctx, req)
+}
+
+// SetLowStockThreshold 设置低库存预警阈值
+func (s *ProductService) SetLowStockThreshold(ctx context.Context, req *proto.SetLowStockThresholdRequest) (*proto.SetLowStockThresholdResponse, error) {
+	return s.client.SetLowStockThreshold(ctx, req)
+}
+
+// AdjustStock 调整库存
+func (s *ProductService) AdjustStock(ctx context.Context, req *proto.AdjustStockRequest) (*proto.AdjustStockResponse, error) {
+	return s.client.AdjustStock(ctx, req)
+}
+
+// GetStockHistory 库存流水
+func (s *ProductService) GetStockHistory(ctx context.Context, req *proto.GetStockHistoryRequest) (*proto.GetStockHistoryResponse, error) {
+	return s.client.GetStockHistory(ctx, req)
+}
+
+// SetProductStatus 修改商品状态
+func (s *ProductService) SetProductStatus(ctx context.Context, req *proto.SetProductStatusRequest) (*proto.SetProductStatusResponse, error) {
+	return s.client.SetProductStatus(ctx, req)
+}
+
+// SchedulePriceChange 创建定时调价
+func (s *ProductService) SchedulePriceChange(ctx context.Context, req *proto.SchedulePriceChangeRequest) (*proto.SchedulePriceChangeResponse, error) {
+	return s.client.SchedulePriceChange(ctx, req)
+}
+
+// CancelPriceSchedule 取消定时调价
+func (s *ProductService) CancelPriceSchedule(ctx context.Context, req *proto.CancelPriceScheduleRequest) (*proto.CancelPriceScheduleResponse, error) {
+	return s.client.CancelPriceSchedule(ctx, req)
+}
+
+// ListPriceSchedules 商品的定时调价列表
+func (s *ProductService) ListPriceSchedules(ctx context.Context, req *proto.ListPriceSchedulesRequest) (*proto.ListPriceSchedulesResponse, error) {
+	return s.client.ListPriceSchedules(ctx, req)
+}
+
+// GetPriceHistory 价格变动记录
+func (s *ProductService) GetPriceHistory(ctx context.Context, req *proto.GetPriceHistoryRequest) (*proto.GetPriceHistoryResponse, error) {
+	return s.client.GetPriceHistory(ctx, req)
+}
+
+// CreateWarehouse 创建仓库
+func (s *ProductService) CreateWarehouse(ctx context.Context, req *proto.CreateWarehouseRequest) (*proto.CreateWarehouseResponse, error) {
+	return s.client.CreateWarehouse(ctx, req)
+}
+
+// UpdateWarehouse 修改仓库
+func (s *ProductService) UpdateWarehouse(ctx context.Context, req *proto.UpdateWarehouseRequest) (*proto.UpdateWarehouseResponse, error) {
+	return s.client.UpdateWarehouse(ctx, req)
+}
+
+// ListWarehouses 仓库列表
+func (s *ProductService) ListWarehouses(ctx context.Context, req *proto.ListWarehousesRequest) (*proto.ListWarehousesResponse, error) {
+	return s.client.ListWarehouses(ctx, req)
+}
+
+// GetWarehouseStock 商品在各仓库的库存
+func (s *ProductService) GetWarehouseStock(ctx context.Context, req *proto.GetWarehouseStockRequest) (*proto.GetWarehouseStockResponse, error) {
+	return s.client.GetWarehouseStock(ctx, req)
+}
+
+// ImportProducts 批量导入商品
+func (s *ProductService) ImportProducts(ctx context.Context) (proto.ProductService_ImportProductsClient, error) {
+	return s.client.ImportProducts(ctx)
+}
+
+// ExportProducts 流式导出商品
+func (s *ProductService) ExportProducts(ctx context.Context, req *proto.ExportProductsRequest) (proto.ProductService_ExportProductsClient, error) {
+	return s.client.ExportProducts(ctx, req)
+}
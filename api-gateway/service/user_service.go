@@ -43,6 +43,16 @@ func (s *UserService) Login(ctx context.Context, req *proto.LoginRequest) (*prot
 	return s.client.Login(ctx, req)
 }
 
+// RefreshToken 用 refresh token 换取新的 token
+func (s *UserService) RefreshToken(ctx context.Context, req *proto.RefreshTokenRequest) (*proto.RefreshTokenResponse, error) {
+	return s.client.RefreshToken(ctx, req)
+}
+
+// Logout 退出登录
+func (s *UserService) Logout(ctx context.Context, req *proto.LogoutRequest) (*proto.LogoutResponse, error) {
+	return s.client.Logout(ctx, req)
+}
+
 // GetUserInfo 获取用户信息
 func (s *UserService) GetUserInfo(ctx context.Context, req *proto.UserInfoRequest) (*proto.UserInfoResponse, error) {
 	return s.client.GetUserInfo(ctx, req)
@@ -62,3 +72,48 @@ func (s *UserService) DeleteUser(ctx context.Context, req *proto.DeleteUserReque
 func (s *UserService) ListUsers(ctx context.Context, req *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
 	return s.client.ListUsers(ctx, req)
 }
+
+// ListStockAlerts 库存预警列表
+func (s *UserService) ListStockAlerts(ctx context.Context, req *proto.ListStockAlertsRequest) (*proto.ListStockAlertsResponse, error) {
+	return s.client.ListStockAlerts(ctx, req)
+}
+
+// AddWishlistItem 收藏商品
+func (s *UserService) AddWishlistItem(ctx context.Context, req *proto.AddWishlistItemRequest) (*proto.AddWishlistItemResponse, error) {
+	return s.client.AddWishlistItem(ctx, req)
+}
+
+// RemoveWishlistItem 取消收藏
+func (s *UserService) RemoveWishlistItem(ctx context.Context, req *proto.RemoveWishlistItemRequest) (*proto.RemoveWishlistItemResponse, error) {
+	return s.client.RemoveWishlistItem(ctx, req)
+}
+
+// ListWishlist 收藏夹
+func (s *UserService) ListWishlist(ctx context.Context, req *proto.ListWishlistRequest) (*proto.ListWishlistResponse, error) {
+	return s.client.ListWishlist(ctx, req)
+}
+
+// SubscribeProduct 订阅到货或降价提醒
+func (s *UserService) SubscribeProduct(ctx context.Context, req *proto.SubscribeProductRequest) (*proto.SubscribeProductResponse, error) {
+	return s.client.SubscribeProduct(ctx, req)
+}
+
+// UnsubscribeProduct 取消订阅
+func (s *UserService) UnsubscribeProduct(ctx context.Context, req *proto.UnsubscribeProductRequest) (*proto.UnsubscribeProductResponse, error) {
+	return s.client.UnsubscribeProduct(ctx, req)
+}
+
+// ListSubscriptions 订阅列表
+func (s *UserService) ListSubscriptions(ctx context.Context, req *proto.ListSubscriptionsRequest) (*proto.ListSubscriptionsResponse, error) {
+	return s.client.ListSubscriptions(ctx, req)
+}
+
+// ListNotifications 通知列表
+func (s *UserService) ListNotifications(ctx context.Context, req *proto.ListNotificationsRequest) (*proto.ListNotificationsResponse, error) {
+	return s.client.ListNotifications(ctx, req)
+}
+
+// MarkNotificationsRead 标记通知为已读
+func (s *UserService) MarkNotificationsRead(ctx context.Context, req *proto.MarkNotificationsReadRequest) (*proto.MarkNotificationsReadResponse, error) {
+	return s.client.MarkNotificationsRead(ctx, req)
+}
@@ -0,0 +1,22 @@
+// Package storage 文件存储，目前提供本地文件系统实现，
+// 后续接入 S3 兼容的对象存储只需实现 Storage 接口
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrInvalidKey 存储路径不合法
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage 文件存储接口，key 为形如 products/1/abc.jpg 的相对路径
+type Storage interface {
+	// Save 保存文件，已存在时覆盖
+	Save(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Delete 删除文件，文件不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// URL 返回文件的访问地址
+	URL(key string) string
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BloomFilter 基于 Redis 位图的布隆过滤器，多个实例共享同一份数据。
+// 判断为不存在时一定不存在，判断为存在时可能误判
+type BloomFilter struct {
+	rdb    *redis.Client
+	key    string
+	bits   uint64
+	hashes int
+}
+
+// NewBloomFilter 按预计元素数量和期望误判率计算位图大小和哈希函数个数
+func NewBloomFilter(rdb *redis.Client, key string, expectedItems uint64, falsePositiveRate float64) *BloomFilter {
+	if expectedItems == 0 {
+		expectedItems = 1
+	}
+	bits := uint64(math.Ceil(-float64(expectedItems) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Round(float64(bits) / float64(expectedItems) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &BloomFilter{rdb: rdb, key: key, bits: bits, hashes: hashes}
+}
+
+// offsets 使用双重哈希计算元素对应的位
+func (f *BloomFilter) offsets(item string) []int64 {
+	h := fnv.New64a()
+	h.Write([]byte(item))
+	h1 := h.Sum64()
+	h2 := h1>>32 | h1<<32
+	offsets := make([]int64, f.hashes)
+	for i := range offsets {
+		offsets[i] = int64((h1 + uint64(i)*h2) % f.bits)
+	}
+	return offsets
+}
+
+// Add 加入元素，正在重建时同时写入重建中的临时 key，避免替换后丢失
+func (f *BloomFilter) Add(ctx context.Context, items ...string) error {
+	if err := f.add(ctx, f.key, items); err != nil {
+		return err
+	}
+	if f.rdb.Exists(ctx, f.rebuildKey()).Val() > 0 {
+		return f.add(ctx, f.rebuildKey(), items)
+	}
+	return nil
+}
+
+func (f *BloomFilter) rebuildKey() string {
+	return f.key + ":rebuilding"
+}
+
+func (f *BloomFilter) add(ctx context.Context, key string, items []string) error {
+	if len(items) == 0 {
+		return nil
+	}
+	pipe := f.rdb.Pipeline()
+	for _, item := range items {
+		for _, offset := range f.offsets(item) {
+			pipe.SetBit(ctx, key, offset, 1)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// MightContain 判断元素是否可能存在；过滤器尚未构建时视为存在
+func (f *BloomFilter) MightContain(ctx context.Context, item string) (bool, error) {
+	pipe := f.rdb.Pipeline()
+	exists := pipe.Exists(ctx, f.key)
+	var bits []*redis.IntCmd
+	for _, offset := range f.offsets(item) {
+		bits = append(bits, pipe.GetBit(ctx, f.key, offset))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return true, err
+	}
+	if exists.Val() == 0 {
+		return true, nil
+	}
+	for _, bit := range bits {
+		if bit.Val() == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Rebuild 重新构建过滤器。each 依次产出全部元素，构建在临时 key 上完成后整体替换，
+// 构建期间读取的仍是旧数据
+func (f *BloomFilter) Rebuild(ctx context.Context, each func(add func(items ...string) error) error) error {
+	tmpKey := f.rebuildKey()
+	if err := f.rdb.Del(ctx, tmpKey).Err(); err != nil {
+		return err
+	}
+	// 先写入最后一位，保证数据为空时临时 key 也存在
+	if err := f.rdb.SetBit(ctx, tmpKey, int64(f.bits-1), 0).Err(); err != nil {
+		return err
+	}
+	err := each(func(items ...string) error {
+		return f.add(ctx, tmpKey, items)
+	})
+	if err != nil {
+		f.rdb.Del(ctx, tmpKey)
+		return err
+	}
+	return f.rdb.Rename(ctx, tmpKey, f.key).Err()
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilterMightContain(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	f := NewBloomFilter(rdb, "bloom:products", 1000, 0.01)
+	ctx := context.Background()
+
+	// 尚未构建时视为存在
+	if ok, err := f.MightContain(ctx, "1"); err != nil || !ok {
+		t.Fatalf("MightContain before build = %v, %v, want true", ok, err)
+	}
+
+	var added []string
+	for i := 0; i < 100; i++ {
+		added = append(added, fmt.Sprint(i))
+	}
+	if err := f.Add(ctx, added...); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range added {
+		if ok, err := f.MightContain(ctx, item); err != nil || !ok {
+			t.Fatalf("MightContain(%s) = %v, %v, want true", item, ok, err)
+		}
+	}
+	falsePositives := 0
+	for i := 1000; i < 2000; i++ {
+		if ok, _ := f.MightContain(ctx, fmt.Sprint(i)); ok {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("false positives = %d/1000, want about 1%%", falsePositives)
+	}
+}
+
+func TestBloomFilterRebuild(t *testing.T) {
+	errRebuild := errors.New("db down")
+	tests := []struct {
+		name    string
+		items   []string
+		during  []string // 重建期间通过 Add 加入的元素
+		err     error
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "replaces old data",
+			items:   []string{"new"},
+			present: []string{"new"},
+			absent:  []string{"old"},
+		},
+		{
+			name:    "keeps items added while rebuilding",
+			items:   []string{"new"},
+			during:  []string{"added"},
+			present: []string{"new", "added"},
+			absent:  []string{"old"},
+		},
+		{
+			name:   "empty rebuild rejects everything",
+			absent: []string{"old", "new"},
+		},
+		{
+			name:    "failed rebuild keeps old data",
+			items:   []string{"new"},
+			err:     errRebuild,
+			present: []string{"old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, mr := newTestRedis(t)
+			f := NewBloomFilter(rdb, "bloom:products", 1000, 0.01)
+			ctx := context.Background()
+			if err := f.Add(ctx, "old"); err != nil {
+				t.Fatal(err)
+			}
+			err := f.Rebuild(ctx, func(add func(items ...string) error) error {
+				if err := add(tt.items...); err != nil {
+					return err
+				}
+				if err := f.Add(ctx, tt.during...); err != nil {
+					return err
+				}
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Rebuild error = %v, want %v", err, tt.err)
+			}
+			if mr.Exists(f.rebuildKey()) {
+				t.Errorf("rebuild key %s still exists", f.rebuildKey())
+			}
+			for _, item := range tt.present {
+				if ok, _ := f.MightContain(ctx, item); !ok {
+					t.Errorf("MightContain(%s) = false, want true", item)
+				}
+			}
+			for _, item := range tt.absent {
+				if ok, _ := f.MightContain(ctx, item); ok {
+					t.Errorf("MightContain(%s) = true, want false", item)
+				}
+			}
+		})
+	}
+}
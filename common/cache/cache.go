@@ -0,0 +1,244 @@
+// Package cache 基于 Redis 的旁路缓存，防止缓存击穿、穿透和雪崩：
+// 同一 key 的并发回源合并为一次，不存在的数据缓存空值，过期时间加随机抖动
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound 数据不存在，loader 返回该错误时会缓存空值
+var ErrNotFound = errors.New("cache: not found")
+
+// notFoundValue 空值占位，正常数据经 JSON 编码后不会是空串
+const notFoundValue = ""
+
+type Options struct {
+	Name        string        // 缓存名称，用于统计
+	TTL         time.Duration // 正常数据的过期时间
+	NotFoundTTL time.Duration // 空值的过期时间，为 0 时不缓存空值
+	Jitter      float64       // 过期时间随机增加的比例，如 0.2 表示增加 0~20%
+	TagPrefix   string        // 标签集合 key 的前缀，多个缓存使用相同前缀时可共享标签
+}
+
+// Stats 命中统计
+type Stats struct {
+	Name   string
+	TTL    time.Duration
+	Hits   int64 // 命中缓存（含空值）
+	Misses int64 // 未命中，需要回源
+	Loads  int64 // 实际回源次数，并发未命中会被合并
+	Errors int64 // 回源失败次数
+}
+
+// HitRate 命中率
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Cache 旁路缓存
+type Cache struct {
+	rdb   *redis.Client
+	opts  Options
+	group singleflight.Group
+
+	hits, misses, loads, errors atomic.Int64
+}
+
+func New(rdb *redis.Client, opts Options) *Cache {
+	return &Cache{rdb: rdb, opts: opts}
+}
+
+// Get 读取 key 对应的数据并解码到 dest；未命中时调用 load 回源并写入缓存。
+// 数据不存在时返回 ErrNotFound，Redis 不可用时直接回源
+func (c *Cache) Get(ctx context.Context, key string, dest interface{}, load func(ctx context.Context) (interface{}, error)) error {
+	return c.get(ctx, key, dest, func(ctx context.Context) (interface{}, []string, error) {
+		value, err := load(ctx)
+		return value, nil, err
+	})
+}
+
+// GetTagged 与 Get 相同，load 额外返回数据关联的标签，可通过 InvalidateTags 按标签批量失效
+func (c *Cache) GetTagged(ctx context.Context, key string, dest interface{}, load func(ctx context.Context) (interface{}, []string, error)) error {
+	return c.get(ctx, key, dest, load)
+}
+
+func (c *Cache) get(ctx context.Context, key string, dest interface{}, load func(ctx context.Context) (interface{}, []string, error)) error {
+	val, err := c.rdb.Get(ctx, key).Result()
+	if err == nil {
+		if val == notFoundValue {
+			c.hits.Add(1)
+			return ErrNotFound
+		}
+		if json.Unmarshal([]byte(val), dest) == nil {
+			c.hits.Add(1)
+			return nil
+		}
+	}
+	c.misses.Add(1)
+
+	// 合并同一 key 的并发回源；回源不受单个请求取消的影响
+	loadCtx := context.WithoutCancel(ctx)
+	bytes, err, _ := c.group.Do(key, func() (interface{}, error) {
+		c.loads.Add(1)
+		value, tags, err := load(loadCtx)
+		if errors.Is(err, ErrNotFound) {
+			if c.opts.NotFoundTTL > 0 {
+				c.rdb.Set(loadCtx, key, notFoundValue, c.jitter(c.opts.NotFoundTTL))
+			}
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			c.errors.Add(1)
+			return nil, err
+		}
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			c.errors.Add(1)
+			return nil, err
+		}
+		ttl := c.jitter(c.opts.TTL)
+		pipe := c.rdb.TxPipeline()
+		pipe.Set(loadCtx, key, bytes, ttl)
+		for _, tag := range tags {
+			// 标签集合的过期时间不短于其中的 key，失效后残留的成员无副作用
+			pipe.SAdd(loadCtx, c.tagKey(tag), key)
+			pipe.ExpireNX(loadCtx, c.tagKey(tag), ttl)
+			pipe.ExpireGT(loadCtx, c.tagKey(tag), ttl)
+		}
+		pipe.Exec(loadCtx)
+		return bytes, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes.([]byte), dest)
+}
+
+// GetMany 使用 MGET 批量读取，命中的数据通过 dest(i) 返回的对象解码。
+// 返回未命中的 key 下标，以及命中空值（数据不存在）的 key 下标；Redis 不可用时全部视为未命中
+func (c *Cache) GetMany(ctx context.Context, keys []string, dest func(i int) interface{}) (misses, notFound []int) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	vals, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		vals = make([]interface{}, len(keys))
+	}
+	for i, val := range vals {
+		str, ok := val.(string)
+		switch {
+		case !ok:
+			misses = append(misses, i)
+		case str == notFoundValue:
+			notFound = append(notFound, i)
+		case json.Unmarshal([]byte(str), dest(i)) != nil:
+			misses = append(misses, i)
+		}
+	}
+	c.hits.Add(int64(len(keys) - len(misses)))
+	c.misses.Add(int64(len(misses)))
+	return misses, notFound
+}
+
+// SetMany 批量写入缓存，values 为 key 到数据的映射
+func (c *Cache) SetMany(ctx context.Context, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	pipe := c.rdb.Pipeline()
+	for key, value := range values {
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, key, bytes, c.jitter(c.opts.TTL))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// SetNotFound 为不存在的数据写入空值缓存
+func (c *Cache) SetNotFound(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 || c.opts.NotFoundTTL <= 0 {
+		return nil
+	}
+	pipe := c.rdb.Pipeline()
+	for _, key := range keys {
+		pipe.Set(ctx, key, notFoundValue, c.jitter(c.opts.NotFoundTTL))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Set 直接写入缓存
+func (c *Cache) Set(ctx context.Context, key string, value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Set(ctx, key, bytes, c.jitter(c.opts.TTL)).Err()
+}
+
+// Delete 删除缓存
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.rdb.Del(ctx, keys...).Err()
+}
+
+// InvalidateTags 删除带有任一标签的缓存
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	pipe := c.rdb.Pipeline()
+	members := make([]*redis.StringSliceCmd, 0, len(tags))
+	for _, tag := range tags {
+		members = append(members, pipe.SMembers(ctx, c.tagKey(tag)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+	keys := make([]string, 0, len(tags))
+	for i, tag := range tags {
+		keys = append(keys, c.tagKey(tag))
+		keys = append(keys, members[i].Val()...)
+	}
+	return c.rdb.Del(ctx, keys...).Err()
+}
+
+// Stats 返回当前实例的命中统计
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Name:   c.opts.Name,
+		TTL:    c.opts.TTL,
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Loads:  c.loads.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+func (c *Cache) tagKey(tag string) string {
+	return c.opts.TagPrefix + tag
+}
+
+// jitter 在 ttl 基础上随机增加一段时间，避免大量 key 同时过期
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if c.opts.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*c.opts.Jitter*float64(ttl))
+}
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type testItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, mr
+}
+
+func TestCacheGet(t *testing.T) {
+	errLoad := errors.New("db down")
+	tests := []struct {
+		name        string
+		notFoundTTL time.Duration
+		value       interface{}
+		err         error
+		wantErr     error
+		wantLoads   int64 // 连续两次 Get 的回源次数
+	}{
+		{name: "value is cached", value: testItem{ID: 1, Name: "a"}, wantLoads: 1},
+		{name: "not found is cached", notFoundTTL: time.Minute, err: ErrNotFound, wantErr: ErrNotFound, wantLoads: 1},
+		{name: "not found without ttl is not cached", err: ErrNotFound, wantErr: ErrNotFound, wantLoads: 2},
+		{name: "load error is not cached", err: errLoad, wantErr: errLoad, wantLoads: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, _ := newTestRedis(t)
+			c := New(rdb, Options{TTL: time.Minute, NotFoundTTL: tt.notFoundTTL})
+			load := func(ctx context.Context) (interface{}, error) {
+				return tt.value, tt.err
+			}
+			for i := 0; i < 2; i++ {
+				var got testItem
+				err := c.Get(context.Background(), "item:1", &got, load)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Get #%d error = %v, want %v", i+1, err, tt.wantErr)
+				}
+				if err == nil && got != tt.value {
+					t.Fatalf("Get #%d = %+v, want %+v", i+1, got, tt.value)
+				}
+			}
+			if loads := c.Stats().Loads; loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+		})
+	}
+}
+
+func TestCacheGetMergesConcurrentLoads(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	c := New(rdb, Options{TTL: time.Minute})
+	const callers = 10
+	release := make(chan struct{})
+	load := func(ctx context.Context) (interface{}, error) {
+		<-release
+		return testItem{ID: 1, Name: "a"}, nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var got testItem
+			errs <- c.Get(context.Background(), "item:1", &got, load)
+		}()
+	}
+	// 所有调用都未命中后再放行回源
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().Misses < callers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Get error = %v", err)
+		}
+	}
+	if loads := c.Stats().Loads; loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+}
+
+func TestCacheGetMany(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	c := New(rdb, Options{TTL: time.Minute, NotFoundTTL: time.Minute})
+	ctx := context.Background()
+	if err := c.Set(ctx, "item:1", testItem{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetNotFound(ctx, "item:2"); err != nil {
+		t.Fatal(err)
+	}
+	rdb.Set(ctx, "item:4", "{broken", time.Minute)
+
+	keys := []string{"item:1", "item:2", "item:3", "item:4"}
+	items := make([]testItem, len(keys))
+	misses, notFound := c.GetMany(ctx, keys, func(i int) interface{} { return &items[i] })
+	if want := []int{2, 3}; !equalInts(misses, want) {
+		t.Errorf("misses = %v, want %v", misses, want)
+	}
+	if want := []int{1}; !equalInts(notFound, want) {
+		t.Errorf("notFound = %v, want %v", notFound, want)
+	}
+	if items[0].ID != 1 {
+		t.Errorf("items[0] = %+v, want ID 1", items[0])
+	}
+}
+
+func TestCacheInvalidateTags(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	c := New(rdb, Options{TTL: time.Minute, TagPrefix: "tag:"})
+	ctx := context.Background()
+	tagged := map[string][]string{
+		"list:1": {"category:1"},
+		"list:2": {"category:1", "category:2"},
+		"list:3": {"category:2"},
+	}
+	for key, tags := range tagged {
+		var got testItem
+		err := c.GetTagged(ctx, key, &got, func(ctx context.Context) (interface{}, []string, error) {
+			return testItem{Name: key}, tags, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.InvalidateTags(ctx, "category:1"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"list:1": false, "list:2": false, "list:3": true, "tag:category:1": false} {
+		if got := mr.Exists(key); got != want {
+			t.Errorf("key %s exists = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -17,11 +20,30 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
+// AccessTokenTTL access token 的有效期，过期后用 refresh token 换取新的 token
+const AccessTokenTTL = 15 * time.Minute
+
+func init() {
+	// 签发时间等精确到毫秒，吊销用户所有 token 时才能区分同一秒内吊销前后签发的 token
+	jwt.TimePrecision = time.Millisecond
+}
+
 // JWTSecret 密钥，从环境变量中读取
 var JWTSecret = []byte(getEnvOrDefault("JWT_SECRET", "your-secret-key"))
 
+// ServiceJWTSecret 内部服务 token 的签名密钥，与用户 token 分开，拿到用户密钥也无法伪造服务身份
+var ServiceJWTSecret = []byte(getEnvOrDefault("SERVICE_JWT_SECRET", "your-service-secret-key"))
+
+const (
+	// ServiceAudience 内部服务 token 的受众，用户 token 不带受众
+	ServiceAudience = "internal-service"
+	// serviceTokenTTL 服务 token 在每次调用时签发，有效期很短
+	serviceTokenTTL = time.Minute
+)
+
 // getEnvOrDefault 从环境变量获取值，如果不存在则返回默认值
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -34,40 +56,81 @@ func getEnvOrDefault(key, defaultValue string) string {
 type Claims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
 // 不需要鉴权的接口白名单
 var noAuthMethods = map[string]bool{
-	"/proto.UserService/Register":        true,
-	"/proto.UserService/Login":           true,
-	"/proto.ProductService/ListProducts": true,
-	"/proto.ProductService/GetProduct":   true,
+	"/proto.UserService/Register":            true,
+	"/proto.UserService/Login":               true,
+	"/proto.UserService/RefreshToken":        true,
+	"/proto.UserService/Logout":              true,
+	"/proto.ProductService/ListProducts":     true,
+	"/proto.ProductService/GetProduct":       true,
+	"/proto.ProductService/BatchGetProducts": true,
+	"/proto.ProductService/ListCategories":   true,
+	"/proto.ProductService/SearchProducts":   true,
+	"/proto.ProductService/ListReviews":      true,
+	"/proto.OrderService/ListFlashSales":     true,
+	"/proto.OrderService/GetRelatedProducts": true,
+}
+
+// GenerateToken 生成JWT token，每个 token 带有唯一的 jti，用于吊销
+func GenerateToken(userID int64, username, role string) (string, error) {
+	claims, err := newClaims(userID, username, role, AccessTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	return signToken(claims, JWTSecret)
+}
+
+// GenerateServiceToken 生成内部服务之间调用使用的 token，用户ID为 0，用户名为服务名。
+// 使用单独的密钥签名并带有服务受众
+func GenerateServiceToken(name string) (string, error) {
+	claims, err := newClaims(0, name, RoleService, serviceTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	claims.Issuer = name
+	claims.Audience = jwt.ClaimStrings{ServiceAudience}
+	return signToken(claims, ServiceJWTSecret)
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID int64, username string) (string, error) {
+// newClaims 创建有效期为 ttl 的 Claims
+func newClaims(userID int64, username, role string, ttl time.Duration) (*Claims, error) {
 	// 设置token的过期时间
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(ttl)
+
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		return nil, err
+	}
 
 	// 创建Claims
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "user-service",
 			Subject:   username,
+			ID:        hex.EncodeToString(tokenID),
 		},
 	}
+	return claims, nil
+}
 
+// signToken 用 key 签名 token
+func signToken(claims *Claims, key []byte) (string, error) {
 	// 创建token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// 签名token
-	tokenString, err := token.SignedString(JWTSecret)
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", err
 	}
@@ -75,13 +138,58 @@ func GenerateToken(userID int64, username string) (string, error) {
 	return tokenString, nil
 }
 
+// isServiceToken 是否为内部服务 token
+func isServiceToken(claims *Claims) bool {
+	return slices.Contains(claims.Audience, ServiceAudience)
+}
+
 // JWTMiddleware 是一个 gRPC 中间件，用于验证JWT token
 func JWTMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	// 检查是否在白名单中
+	// 检查是否在白名单中；白名单接口携带有效 token 时同样写入用户信息，便于按角色返回不同内容
 	if noAuthMethods[info.FullMethod] {
+		if claims, err := authenticate(ctx); err == nil {
+			ctx = context.WithValue(ctx, "user", claims)
+		}
 		return handler(ctx, req)
 	}
 
+	claims, err := authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 将用户信息存储到上下文中
+	newCtx := context.WithValue(ctx, "user", claims)
+	return handler(newCtx, req)
+}
+
+// JWTStreamMiddleware 是流式接口的 JWT 验证中间件
+func JWTStreamMiddleware(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if noAuthMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+
+	claims, err := authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	newCtx := context.WithValue(ss.Context(), "user", claims)
+	return handler(srv, &authedServerStream{ServerStream: ss, ctx: newCtx})
+}
+
+// authedServerStream 携带用户信息上下文的 ServerStream
+type authedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate 从 gRPC 元数据中解析并验证 token
+func authenticate(ctx context.Context) (*Claims, error) {
 	// 从上下文中获取元数据
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -108,20 +216,23 @@ func JWTMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerI
 		if err == ErrExpiredToken {
 			return nil, status.Error(codes.Unauthenticated, "token has expired")
 		}
+		if err == ErrRevokedToken {
+			return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+		}
 		return nil, status.Error(codes.Unauthenticated, "invalid token")
 	}
-
-	// 将用户信息存储到上下文中
-	newCtx := context.WithValue(ctx, "user", claims)
-	return handler(newCtx, req)
+	return claims, nil
 }
 
-// ParseToken 解析JWT token
+// ParseToken 解析JWT token，并检查是否已被吊销
 func ParseToken(tokenString string) (*Claims, error) {
-	// 解析token
+	// 解析token，带服务受众的 token 用服务密钥校验，其余用用户密钥校验
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if claims, ok := token.Claims.(*Claims); ok && isServiceToken(claims) {
+			return ServiceJWTSecret, nil
+		}
 		return JWTSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -140,6 +251,14 @@ func ParseToken(tokenString string) (*Claims, error) {
 	if !ok {
 		return nil, ErrInvalidToken
 	}
+	// 服务角色只能来自服务密钥签名的 token
+	if (claims.Role == RoleService) != isServiceToken(claims) {
+		return nil, ErrInvalidToken
+	}
+
+	if isRevoked(context.Background(), claims) {
+		return nil, ErrRevokedToken
+	}
 
 	return claims, nil
 }
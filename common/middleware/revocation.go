@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// revokedTokenKeyPrefix 被吊销的 access token，按 jti 记录到其过期为止
+	revokedTokenKeyPrefix = "jwt:revoked:"
+	// revokedUserKeyPrefix 用户的吊销时间（Unix 毫秒），在此之前签发的 access token 全部失效
+	revokedUserKeyPrefix = "jwt:revoked-user:"
+)
+
+// tokenDenylist 保存吊销记录的 Redis，未设置时不检查吊销
+var tokenDenylist *redis.Client
+
+// SetTokenDenylist 设置保存吊销记录的 Redis，各服务和网关启动时调用
+func SetTokenDenylist(rdb *redis.Client) {
+	tokenDenylist = rdb
+}
+
+// RevokeToken 吊销一个 access token，记录保留到 token 过期
+func RevokeToken(ctx context.Context, claims *Claims) error {
+	if tokenDenylist == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return tokenDenylist.Set(ctx, revokedTokenKeyPrefix+claims.ID, 1, ttl).Err()
+}
+
+// RevokeUserTokens 吊销用户此前签发的所有 access token，如修改密码后。
+// 之后签发的 token 不受影响，记录保留一个 token 有效期
+func RevokeUserTokens(ctx context.Context, userID int64) error {
+	if tokenDenylist == nil {
+		return nil
+	}
+	key := revokedUserKeyPrefix + strconv.FormatInt(userID, 10)
+	return tokenDenylist.Set(ctx, key, time.Now().UnixMilli(), AccessTokenTTL).Err()
+}
+
+// isRevoked 检查 token 是否已被吊销。Redis 不可用时只记录日志并放行，
+// access token 的有效期很短，不因 Redis 故障让所有请求失败
+func isRevoked(ctx context.Context, claims *Claims) bool {
+	if tokenDenylist == nil {
+		return false
+	}
+	pipe := tokenDenylist.Pipeline()
+	tokenCmd := pipe.Exists(ctx, revokedTokenKeyPrefix+claims.ID)
+	userCmd := pipe.Get(ctx, revokedUserKeyPrefix+strconv.FormatInt(claims.UserID, 10))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Failed to check token revocation: %v", err)
+		return false
+	}
+	if claims.ID != "" && tokenCmd.Val() > 0 {
+		return true
+	}
+	revokedAt, err := userCmd.Int64()
+	if err != nil {
+		return false
+	}
+	// token 的签发时间精确到毫秒，与吊销时间同一毫秒签发的也视为已吊销
+	return claims.IssuedAt == nil || claims.IssuedAt.UnixMilli() <= revokedAt
+}
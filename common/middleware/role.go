@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 用户角色
+const (
+	RoleCustomer = "customer"
+	RoleSupport  = "support"
+	RoleFinance  = "finance"
+	RoleAdmin    = "admin"
+	// RoleService 内部服务之间调用的身份，不会分配给用户
+	RoleService = "service"
+)
+
+// ClaimsFromContext 从上下文中取出 JWTMiddleware 写入的用户信息
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value("user").(*Claims)
+	return claims, ok && claims != nil
+}
+
+// HasRole 判断当前用户是否拥有给定角色之一
+func HasRole(ctx context.Context, roles ...string) bool {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, role := range roles {
+		if claims.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole 校验当前用户角色，不满足时返回 PermissionDenied
+func RequireRole(ctx context.Context, roles ...string) error {
+	if _, ok := ClaimsFromContext(ctx); !ok {
+		return status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	if !HasRole(ctx, roles...) {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return nil
+}
+
+// ForwardAuth 将调用方的 authorization 元数据透传给下游 gRPC 服务
+func ForwardAuth(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if auth := md.Get("authorization"); len(auth) > 0 {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", auth[0])
+	}
+	return ctx
+}
+
+// ServiceContext 以服务身份调用下游 gRPC 服务，用于只允许内部服务调用的接口
+func ServiceContext(ctx context.Context, name string) (context.Context, error) {
+	token, err := GenerateServiceToken(name)
+	if err != nil {
+		return nil, err
+	}
+	return WithToken(ctx, token), nil
+}
+
+// WithToken 将 token 写入发往下游 gRPC 服务的 authorization 元数据，用于没有调用方请求的后台任务
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
@@ -9,9 +9,11 @@ import (
 	"order-service/service"
 	"os"
 	"strings"
+	"time"
 
 	pb "common/proto/gen/order"
 	pbProduct "common/proto/gen/product"
+	pbUser "common/proto/gen/user"
 
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
@@ -37,9 +39,17 @@ func main() {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	if err := db.AutoMigrate(&model.Order{}, &model.OrderItem{}); err != nil {
+	// 完成时间字段上线前已完成的订单没有记录完成时间，迁移后以最后修改时间代替
+	hasCompletedAt := db.Migrator().HasColumn(&model.Order{}, "CompletedAt")
+	if err := db.AutoMigrate(&model.Order{}, &model.OrderItem{}, &model.ArchivedOrder{}, &model.ArchivedOrderItem{}, &model.FlashSale{}, &model.FlashSaleOrder{}, &model.CoPurchase{}); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
+	if !hasCompletedAt {
+		if err := db.Model(&model.Order{}).Where("status = ?", int(pb.OrderStatus_COMPLETED)).
+			UpdateColumn("completed_at", gorm.Expr("updated_at")).Error; err != nil {
+			log.Fatalf("failed to migrate order completed_at: %v", err)
+		}
+	}
 
 	// 连接商品服务
 	productAddr := os.Getenv("PRODUCT_SERVICE_ADDR")
@@ -50,12 +60,27 @@ func main() {
 	defer productConn.Close()
 	productClient := pbProduct.NewProductServiceClient(productConn)
 
+	// 连接用户服务
+	userAddr := os.Getenv("USER_SERVICE_ADDR")
+	userConn, err := grpc.Dial(userAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("failed to connect to user service: %v", err)
+	}
+	defer userConn.Close()
+	userClient := pbUser.NewUserServiceClient(userConn)
+
 	// 初始化 Redis
 	redisAddr := fmt.Sprintf("%s:%s",
 		os.Getenv("REDIS_HOST"),
 		os.Getenv("REDIS_PORT"),
 	)
 	service.InitRedis(redisAddr)
+	middleware.SetTokenDenylist(service.RedisClient)
+
+	// 下单时的仓库分配策略：priority、nearest 或 split
+	if err := service.ConfigureAllocationStrategy(os.Getenv("STOCK_ALLOCATION_STRATEGY")); err != nil {
+		log.Fatalf("failed to configure allocation strategy: %v", err)
+	}
 
 	// 初始化 Kafka
 	kafkaBrokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
@@ -76,10 +101,28 @@ func main() {
 	// 创建带有 JWT 中间件的 gRPC 服务器
 	s := grpc.NewServer(
 		grpc.UnaryInterceptor(middleware.JWTMiddleware),
+		grpc.StreamInterceptor(middleware.JWTStreamMiddleware),
 	)
-	orderService := service.NewOrderService(db, productClient)
+	orderService := service.NewOrderService(db, productClient, userClient)
 	pb.RegisterOrderServiceServer(s, orderService)
 
+	// 秒杀抢购消息单独使用一个 topic，订单由消费者异步创建
+	flashSaleTopic := os.Getenv("FLASH_SALE_TOPIC")
+	if flashSaleTopic == "" {
+		flashSaleTopic = "flash-sale-purchases"
+	}
+	service.InitFlashSaleProducer(kafkaBrokers, flashSaleTopic)
+	orderService.StartFlashSaleConsumer(kafkaBrokers, flashSaleTopic)
+
+	// 定时计算商品共同购买统计，间隔如 1h
+	recommendationInterval := service.DefaultRecommendationInterval
+	if v := os.Getenv("RECOMMENDATION_INTERVAL"); v != "" {
+		if recommendationInterval, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("invalid RECOMMENDATION_INTERVAL: %v", err)
+		}
+	}
+	orderService.StartRecommendationJob(recommendationInterval)
+
 	log.Printf("Order service listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FlashSale 秒杀活动，活动库存在创建时预热到 Redis，抢购只读写 Redis
+type FlashSale struct {
+	gorm.Model
+	ProductID   int64     `gorm:"not null;index"`
+	SkuID       int64     `gorm:"not null;default:0"`
+	ProductName string    `gorm:"size:128;not null"`
+	SkuSpec     string    `gorm:"size:128"`
+	Price       float64   `gorm:"not null"`
+	Stock       int       `gorm:"not null"`
+	StartAt     time.Time `gorm:"not null"`
+	EndAt       time.Time `gorm:"not null;index"`
+}
+
+// FlashSaleOrder 秒杀订单，每个用户每个活动只能有一条，用于异步创建订单时去重
+type FlashSaleOrder struct {
+	ID          uint  `gorm:"primarykey"`
+	FlashSaleID uint  `gorm:"not null;uniqueIndex:idx_flash_sale_user"`
+	UserID      int64 `gorm:"not null;uniqueIndex:idx_flash_sale_user"`
+	OrderID     uint  `gorm:"not null"`
+	CreatedAt   time.Time
+}
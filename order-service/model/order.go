@@ -1,22 +1,148 @@
 package model
 
 import (
+	"encoding/json"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Order struct {
 	gorm.Model
-	UserID     int64       `gorm:"not null;index"`
-	TotalPrice float64     `gorm:"not null"`
-	Status     int         `gorm:"not null"` // 对应proto中的OrderStatus枚举
-	Items      []OrderItem `gorm:"foreignKey:OrderID"`
+	UserID      int64       `gorm:"not null;index;index:idx_orders_user_total,priority:1"`
+	TotalPrice  float64     `gorm:"not null;index:idx_orders_user_total,priority:2"`
+	Status      int         `gorm:"not null"` // 对应proto中的OrderStatus枚举
+	HiddenAt    *time.Time  // 用户隐藏时间，隐藏后不再出现在用户的订单列表中
+	CompletedAt *time.Time  `gorm:"index"` // 订单完成时间，归档按此时间判断
+	Items       []OrderItem `gorm:"foreignKey:OrderID"`
 }
 
 type OrderItem struct {
 	gorm.Model
 	OrderID     uint    `gorm:"not null;index"`
-	ProductID   int64   `gorm:"not null"`
+	ProductID   int64   `gorm:"not null;index"`
+	SkuID       int64   `gorm:"not null;default:0;index"`
+	SkuSpec     string  `gorm:"size:128"`
 	ProductName string  `gorm:"size:128;not null"`
 	Quantity    int     `gorm:"not null"`
 	Price       float64 `gorm:"not null"`
+	Allocations string  `gorm:"type:text"` // 发货仓库，JSON 格式，如 [{"warehouse_id":1,"quantity":2}]
+}
+
+// Allocation 订单项由某个仓库发货的数量
+type Allocation struct {
+	WarehouseID int64 `json:"warehouse_id"`
+	Quantity    int   `json:"quantity"`
+}
+
+// AllocationList 解析发货仓库
+func (it *OrderItem) AllocationList() []Allocation {
+	var allocations []Allocation
+	if it.Allocations != "" {
+		_ = json.Unmarshal([]byte(it.Allocations), &allocations)
+	}
+	return allocations
+}
+
+// SetAllocations 设置发货仓库
+func (it *OrderItem) SetAllocations(allocations []Allocation) {
+	if len(allocations) == 0 {
+		it.Allocations = ""
+		return
+	}
+	bytes, _ := json.Marshal(allocations)
+	it.Allocations = string(bytes)
+}
+
+// ArchivedOrder 归档订单，沿用原订单 ID，归档后从订单表中移除
+type ArchivedOrder struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      int64   `gorm:"not null;index"`
+	TotalPrice  float64 `gorm:"not null"`
+	Status      int     `gorm:"not null"`
+	HiddenAt    *time.Time
+	CompletedAt *time.Time
+	ArchivedAt  time.Time           `gorm:"not null;index"`
+	Items       []ArchivedOrderItem `gorm:"foreignKey:OrderID"`
+}
+
+type ArchivedOrderItem struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	OrderID     uint    `gorm:"not null;index"`
+	ProductID   int64   `gorm:"not null;index"`
+	SkuID       int64   `gorm:"not null;default:0;index"`
+	SkuSpec     string  `gorm:"size:128"`
+	ProductName string  `gorm:"size:128;not null"`
+	Quantity    int     `gorm:"not null"`
+	Price       float64 `gorm:"not null"`
+	Allocations string  `gorm:"type:text"`
+}
+
+// NewArchivedOrder 由订单生成归档记录
+func NewArchivedOrder(order *Order, archivedAt time.Time) ArchivedOrder {
+	archived := ArchivedOrder{
+		ID:          order.ID,
+		CreatedAt:   order.CreatedAt,
+		UpdatedAt:   order.UpdatedAt,
+		UserID:      order.UserID,
+		TotalPrice:  order.TotalPrice,
+		Status:      order.Status,
+		HiddenAt:    order.HiddenAt,
+		CompletedAt: order.CompletedAt,
+		ArchivedAt:  archivedAt,
+	}
+	for _, it := range order.Items {
+		archived.Items = append(archived.Items, ArchivedOrderItem{
+			ID:          it.ID,
+			CreatedAt:   it.CreatedAt,
+			UpdatedAt:   it.UpdatedAt,
+			OrderID:     it.OrderID,
+			ProductID:   it.ProductID,
+			SkuID:       it.SkuID,
+			SkuSpec:     it.SkuSpec,
+			ProductName: it.ProductName,
+			Quantity:    it.Quantity,
+			Price:       it.Price,
+			Allocations: it.Allocations,
+		})
+	}
+	return archived
+}
+
+// ToOrder 将归档记录还原为订单
+func (a *ArchivedOrder) ToOrder() Order {
+	order := Order{
+		Model: gorm.Model{
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt,
+			UpdatedAt: a.UpdatedAt,
+		},
+		UserID:      a.UserID,
+		TotalPrice:  a.TotalPrice,
+		Status:      a.Status,
+		HiddenAt:    a.HiddenAt,
+		CompletedAt: a.CompletedAt,
+	}
+	for _, it := range a.Items {
+		order.Items = append(order.Items, OrderItem{
+			Model: gorm.Model{
+				ID:        it.ID,
+				CreatedAt: it.CreatedAt,
+				UpdatedAt: it.UpdatedAt,
+			},
+			OrderID:     it.OrderID,
+			ProductID:   it.ProductID,
+			SkuID:       it.SkuID,
+			SkuSpec:     it.SkuSpec,
+			ProductName: it.ProductName,
+			Quantity:    it.Quantity,
+			Price:       it.Price,
+			Allocations: it.Allocations,
+		})
+	}
+	return order
 }
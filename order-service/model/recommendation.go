@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// CoPurchase 商品共同购买统计：同时购买 ProductID 和 RelatedID 的订单数，每对商品双向各一条，由定时任务重新计算，UpdatedAt 为最近一次计算的时间
+type CoPurchase struct {
+	ProductID  int64 `gorm:"primaryKey;autoIncrement:false"`
+	RelatedID  int64 `gorm:"primaryKey;autoIncrement:false"`
+	OrderCount int   `gorm:"not null"`
+	UpdatedAt  time.Time
+}
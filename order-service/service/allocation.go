@@ -0,0 +1,105 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/order"
+	pbProduct "common/proto/gen/product"
+	"context"
+	"fmt"
+	"log"
+	"order-service/model"
+	"strings"
+)
+
+// serviceName 调用只允许内部服务调用的接口时使用的服务身份
+const serviceName = "order-service"
+
+// allocationStrategy 下单时的仓库分配策略
+var allocationStrategy = pbProduct.AllocationStrategy_ALLOCATION_PRIORITY
+
+// ConfigureAllocationStrategy 设置仓库分配策略，可选 priority、nearest、split，为空时使用 priority
+func ConfigureAllocationStrategy(name string) error {
+	if name == "" {
+		return nil
+	}
+	value, ok := pbProduct.AllocationStrategy_value["ALLOCATION_"+strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("invalid allocation strategy %q", name)
+	}
+	allocationStrategy = pbProduct.AllocationStrategy(value)
+	return nil
+}
+
+// orderReference 订单在库存流水中的关联单据
+func orderReference(orderID uint) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// reserveStock 为订单占用库存，并将各订单项的发货仓库写入 order.Items
+func (s *OrderService) reserveStock(ctx context.Context, order *model.Order, req *pb.CreateOrderRequest) error {
+	items := make([]*pbProduct.ReserveStockItem, 0, len(order.Items))
+	for _, it := range order.Items {
+		items = append(items, &pbProduct.ReserveStockItem{
+			ProductId: it.ProductID,
+			SkuId:     it.SkuID,
+			Quantity:  int32(it.Quantity),
+		})
+	}
+	// 占用和释放库存只允许内部服务调用，不透传用户的 token
+	ctx, err := middleware.ServiceContext(ctx, serviceName)
+	if err != nil {
+		return err
+	}
+	resp, err := s.productClient.ReserveStock(ctx, &pbProduct.ReserveStockRequest{
+		Reference: orderReference(order.ID),
+		Items:     items,
+		Strategy:  allocationStrategy,
+		Latitude:  req.ShipToLatitude,
+		Longitude: req.ShipToLongitude,
+	})
+	if err != nil {
+		return err
+	}
+	allocations := make([][]model.Allocation, len(order.Items))
+	for _, a := range resp.Allocations {
+		if int(a.ItemIndex) >= len(allocations) {
+			continue
+		}
+		allocations[a.ItemIndex] = append(allocations[a.ItemIndex], model.Allocation{
+			WarehouseID: a.WarehouseId,
+			Quantity:    int(a.Quantity),
+		})
+	}
+	for i := range order.Items {
+		order.Items[i].SetAllocations(allocations[i])
+	}
+	return nil
+}
+
+// releaseStock 订单创建失败时释放已占用的库存
+func (s *OrderService) releaseStock(ctx context.Context, orderID uint) {
+	ctx, err := middleware.ServiceContext(ctx, serviceName)
+	if err != nil {
+		log.Printf("failed to release stock of order %d: %v", orderID, err)
+		return
+	}
+	resp, err := s.productClient.ReleaseStock(ctx, &pbProduct.ReleaseStockRequest{Reference: orderReference(orderID)})
+	if err != nil {
+		log.Printf("failed to release stock of order %d: %v", orderID, err)
+		return
+	}
+	if !resp.Success {
+		log.Printf("failed to release stock of order %d: %s", orderID, resp.Message)
+	}
+}
+
+func convertAllocationsToPB(allocations []model.Allocation) []*pb.OrderItemAllocation {
+	var result []*pb.OrderItemAllocation
+	for _, a := range allocations {
+		result = append(result, &pb.OrderItemAllocation{
+			WarehouseId: a.WarehouseID,
+			Quantity:    int32(a.Quantity),
+		})
+	}
+	return result
+}
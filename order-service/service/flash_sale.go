@@ -0,0 +1,408 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/order"
+	pbProduct "common/proto/gen/product"
+	"context"
+	"fmt"
+	"log"
+	"order-service/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// flashSaleResultTTL 活动结束后抢购名单和结果的保留时间，便于客户端查询结果
+const flashSaleResultTTL = 24 * time.Hour
+
+// 抢购结果在 Redis 中的写法
+const (
+	flashSaleQueued  = "queued"
+	flashSaleSuccess = "success:" // 后接订单ID
+	flashSaleFailed  = "failed:"  // 后接失败原因
+)
+
+// 秒杀相关的 key 使用相同的 hash tag，保证在 Redis Cluster 中落在同一个槽，可以在同一个脚本中操作
+func flashSaleInfoKey(id uint) string    { return fmt.Sprintf("flashsale:{%d}:info", id) }
+func flashSaleStockKey(id uint) string   { return fmt.Sprintf("flashsale:{%d}:stock", id) }
+func flashSaleUsersKey(id uint) string   { return fmt.Sprintf("flashsale:{%d}:users", id) }
+func flashSaleResultsKey(id uint) string { return fmt.Sprintf("flashsale:{%d}:results", id) }
+
+func flashSaleKeys(id uint) []string {
+	return []string{flashSaleInfoKey(id), flashSaleStockKey(id), flashSaleUsersKey(id), flashSaleResultsKey(id)}
+}
+
+// purchaseScript 校验活动时间、限购和库存后扣减库存并记录用户，全部在 Redis 中原子完成。
+// 抢购名单和结果与活动一起过期。返回 1 成功，0 已售罄，-1 已参与，-2 未开始，-3 已结束，-4 活动不存在或未预热
+var purchaseScript = redis.NewScript(`
+local window = redis.call('HMGET', KEYS[1], 'start_at', 'end_at', 'expire_at')
+if not window[1] then
+	return -4
+end
+local now = tonumber(ARGV[2])
+if now < tonumber(window[1]) then
+	return -2
+end
+if now >= tonumber(window[2]) then
+	return -3
+end
+if redis.call('SISMEMBER', KEYS[3], ARGV[1]) == 1 then
+	return -1
+end
+local stock = tonumber(redis.call('GET', KEYS[2]) or '0')
+if stock <= 0 then
+	return 0
+end
+redis.call('DECR', KEYS[2])
+redis.call('SADD', KEYS[3], ARGV[1])
+redis.call('HSET', KEYS[4], ARGV[1], ARGV[3])
+redis.call('EXPIREAT', KEYS[3], window[3])
+redis.call('EXPIREAT', KEYS[4], window[3])
+return 1
+`)
+
+// refundScript 退回用户的名额和库存，重复调用只退回一次；ARGV[2] 为空时删除抢购结果，否则写入结果
+var refundScript = redis.NewScript(`
+if redis.call('SREM', KEYS[3], ARGV[1]) == 1 then
+	redis.call('INCR', KEYS[2])
+end
+if ARGV[2] == '' then
+	redis.call('HDEL', KEYS[4], ARGV[1])
+else
+	redis.call('HSET', KEYS[4], ARGV[1], ARGV[2])
+end
+return 1
+`)
+
+var flashSaleWriter *kafka.Writer
+
+// InitFlashSaleProducer 初始化秒杀抢购消息的 Kafka 生产者
+func InitFlashSaleProducer(brokers []string, topic string) {
+	flashSaleWriter = &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+}
+
+// CreateFlashSale 创建秒杀活动并将活动库存预热到 Redis（管理员）
+func (s *OrderService) CreateFlashSale(ctx context.Context, req *pb.CreateFlashSaleRequest) (*pb.CreateFlashSaleResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Price <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "price must be positive")
+	}
+	if req.Stock <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "stock must be positive")
+	}
+	startAt, err := time.Parse(time.RFC3339, req.StartAt)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "start_at must be RFC3339")
+	}
+	endAt, err := time.Parse(time.RFC3339, req.EndAt)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "end_at must be RFC3339")
+	}
+	if !endAt.After(startAt) || !endAt.After(time.Now()) {
+		return nil, status.Error(codes.InvalidArgument, "end_at must be after start_at and in the future")
+	}
+
+	resp, err := s.productClient.BatchGetProducts(middleware.ForwardAuth(ctx), &pbProduct.BatchGetProductsRequest{ProductIds: []int64{req.ProductId}})
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to get product info: %v", err)
+	}
+	if len(resp.Products) == 0 {
+		return nil, status.Error(codes.NotFound, "product not found")
+	}
+	product := resp.Products[0]
+	if product.Status != pbProduct.ProductStatus_PRODUCT_STATUS_ACTIVE {
+		return nil, status.Error(codes.FailedPrecondition, "product is not available")
+	}
+	sale := model.FlashSale{
+		ProductID:   product.Id,
+		ProductName: product.Name,
+		Price:       req.Price,
+		Stock:       int(req.Stock),
+		StartAt:     startAt,
+		EndAt:       endAt,
+	}
+	stock := product.Stock
+	if len(product.Skus) > 0 {
+		sku := findSku(product.Skus, req.SkuId)
+		if sku == nil {
+			return nil, status.Error(codes.InvalidArgument, "a valid sku_id is required for products with skus")
+		}
+		sale.SkuID = sku.Id
+		sale.SkuSpec = skuSpec(sku.Attributes)
+		stock = sku.Stock
+	} else if req.SkuId > 0 {
+		return nil, status.Error(codes.InvalidArgument, "product has no sku")
+	}
+	// 订单异步创建时才占用商品库存，活动库存不能超过当前库存
+	if req.Stock > stock {
+		return nil, status.Errorf(codes.FailedPrecondition, "stock must not exceed current product stock %d", stock)
+	}
+
+	if err := s.db.Create(&sale).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to create flash sale")
+	}
+	if err := preloadFlashSale(ctx, &sale); err != nil {
+		log.Printf("failed to preload flash sale %d: %v", sale.ID, err)
+		s.db.Delete(&sale)
+		return nil, status.Error(codes.Internal, "failed to preload flash sale")
+	}
+	return &pb.CreateFlashSaleResponse{FlashSale: convertFlashSaleToPB(&sale, sale.Stock)}, nil
+}
+
+// preloadFlashSale 将活动时间和库存写入 Redis，活动结束一段时间后过期
+func preloadFlashSale(ctx context.Context, sale *model.FlashSale) error {
+	expireAt := sale.EndAt.Add(flashSaleResultTTL)
+	pipe := RedisClient.TxPipeline()
+	pipe.HSet(ctx, flashSaleInfoKey(sale.ID), "start_at", sale.StartAt.Unix(), "end_at", sale.EndAt.Unix(), "expire_at", expireAt.Unix())
+	pipe.ExpireAt(ctx, flashSaleInfoKey(sale.ID), expireAt)
+	pipe.Set(ctx, flashSaleStockKey(sale.ID), sale.Stock, 0)
+	pipe.ExpireAt(ctx, flashSaleStockKey(sale.ID), expireAt)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ListFlashSales 未结束的秒杀活动及剩余库存
+func (s *OrderService) ListFlashSales(ctx context.Context, req *pb.ListFlashSalesRequest) (*pb.ListFlashSalesResponse, error) {
+	var sales []model.FlashSale
+	if err := s.db.Where("end_at > ?", time.Now()).Order("start_at asc, id asc").Find(&sales).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query flash sales")
+	}
+	resp := &pb.ListFlashSalesResponse{}
+	if len(sales) == 0 {
+		return resp, nil
+	}
+	keys := make([]string, 0, len(sales))
+	for i := range sales {
+		keys = append(keys, flashSaleStockKey(sales[i].ID))
+	}
+	// 读取失败时剩余库存按 0 返回
+	values, err := RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Printf("failed to read flash sale stock: %v", err)
+	}
+	for i := range sales {
+		remaining := 0
+		if i < len(values) {
+			if v, ok := values[i].(string); ok {
+				remaining, _ = strconv.Atoi(v)
+			}
+		}
+		resp.FlashSales = append(resp.FlashSales, convertFlashSaleToPB(&sales[i], remaining))
+	}
+	return resp, nil
+}
+
+// PurchaseFlashSale 抢购：在 Redis 中扣减库存后发送到 Kafka 排队，订单由消费者异步创建
+func (s *OrderService) PurchaseFlashSale(ctx context.Context, req *pb.PurchaseFlashSaleRequest) (*pb.PurchaseFlashSaleResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	saleID := uint(req.FlashSaleId)
+	keys := flashSaleKeys(saleID)
+	code, err := purchaseScript.Run(ctx, RedisClient, keys, claims.UserID, time.Now().Unix(), flashSaleQueued).Int()
+	if err != nil {
+		log.Printf("flash sale %d purchase script failed: %v", saleID, err)
+		return nil, status.Error(codes.Unavailable, "flash sale is busy, please try again later")
+	}
+	switch code {
+	case 0:
+		return nil, status.Error(codes.ResourceExhausted, "flash sale is sold out")
+	case -1:
+		return nil, status.Error(codes.AlreadyExists, "you have already purchased in this flash sale")
+	case -2:
+		return nil, status.Error(codes.FailedPrecondition, "flash sale has not started")
+	case -3:
+		return nil, status.Error(codes.FailedPrecondition, "flash sale has ended")
+	case -4:
+		return nil, status.Error(codes.NotFound, "flash sale not found")
+	}
+
+	msg := fmt.Sprintf("flash_sale_purchase|flash_sale_id=%d|user_id=%d|lat=%s|lng=%s", saleID, claims.UserID,
+		strconv.FormatFloat(req.ShipToLatitude, 'f', -1, 64), strconv.FormatFloat(req.ShipToLongitude, 'f', -1, 64))
+	if err := sendFlashSaleMessage(ctx, claims.UserID, msg); err != nil {
+		// 没有进入队列，退回名额
+		log.Printf("failed to enqueue flash sale purchase: %v", err)
+		if err := refundScript.Run(Ctx, RedisClient, keys, claims.UserID, "").Err(); err != nil {
+			log.Printf("failed to refund flash sale %d for user %d: %v", saleID, claims.UserID, err)
+		}
+		return nil, status.Error(codes.Unavailable, "flash sale is busy, please try again later")
+	}
+	return &pb.PurchaseFlashSaleResponse{
+		Status:  pb.FlashSaleResultStatus_FLASH_SALE_QUEUED,
+		Message: "purchase accepted, order is being created",
+	}, nil
+}
+
+// GetFlashSaleResult 查询当前用户的抢购结果，供客户端轮询
+func (s *OrderService) GetFlashSaleResult(ctx context.Context, req *pb.GetFlashSaleResultRequest) (*pb.GetFlashSaleResultResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	value, err := RedisClient.HGet(ctx, flashSaleResultsKey(uint(req.FlashSaleId)), strconv.FormatInt(claims.UserID, 10)).Result()
+	if err == redis.Nil {
+		return &pb.GetFlashSaleResultResponse{Status: pb.FlashSaleResultStatus_FLASH_SALE_NONE}, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "failed to query flash sale result")
+	}
+	switch {
+	case strings.HasPrefix(value, flashSaleSuccess):
+		orderID, _ := strconv.ParseInt(strings.TrimPrefix(value, flashSaleSuccess), 10, 64)
+		return &pb.GetFlashSaleResultResponse{Status: pb.FlashSaleResultStatus_FLASH_SALE_SUCCESS, OrderId: orderID}, nil
+	case strings.HasPrefix(value, flashSaleFailed):
+		return &pb.GetFlashSaleResultResponse{
+			Status:  pb.FlashSaleResultStatus_FLASH_SALE_FAILED,
+			Message: strings.TrimPrefix(value, flashSaleFailed),
+		}, nil
+	}
+	return &pb.GetFlashSaleResultResponse{Status: pb.FlashSaleResultStatus_FLASH_SALE_QUEUED}, nil
+}
+
+// sendFlashSaleMessage 发送抢购消息，按用户分区
+func sendFlashSaleMessage(ctx context.Context, userID int64, msg string) error {
+	if flashSaleWriter == nil {
+		return fmt.Errorf("flash sale writer is not initialized")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return flashSaleWriter.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(strconv.FormatInt(userID, 10)),
+		Value: []byte(msg),
+	})
+}
+
+// StartFlashSaleConsumer 消费抢购消息并逐条创建订单，数据库的写入速度由消费速度控制
+func (s *OrderService) StartFlashSaleConsumer(brokers []string, topic string) {
+	go func() {
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			Topic:    topic,
+			GroupID:  "order-service-flash-sale",
+			MinBytes: 1,
+			MaxBytes: 10e6,
+		})
+		defer r.Close()
+		for {
+			m, err := r.ReadMessage(context.Background())
+			if err != nil {
+				log.Printf("failed to read flash sale message: %v", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			s.handleFlashSaleMessage(string(m.Value))
+		}
+	}()
+}
+
+// handleFlashSaleMessage 处理抢购消息，格式如 "flash_sale_purchase|flash_sale_id=1|user_id=2|lat=31.2|lng=121.4"。
+// 创建成功时记录订单ID；失败时退回名额，用户可以重新抢购
+func (s *OrderService) handleFlashSaleMessage(msg string) {
+	parts := strings.Split(msg, "|")
+	if len(parts) == 0 || parts[0] != "flash_sale_purchase" {
+		return
+	}
+	fields := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			fields[k] = v
+		}
+	}
+	saleID, err1 := strconv.ParseUint(fields["flash_sale_id"], 10, 64)
+	userID, err2 := strconv.ParseInt(fields["user_id"], 10, 64)
+	if err1 != nil || err2 != nil {
+		log.Printf("invalid flash sale message: %s", msg)
+		return
+	}
+	lat, _ := strconv.ParseFloat(fields["lat"], 64)
+	lng, _ := strconv.ParseFloat(fields["lng"], 64)
+
+	keys := flashSaleKeys(uint(saleID))
+	orderID, err := s.createFlashSaleOrder(context.Background(), uint(saleID), userID, lat, lng)
+	if err != nil {
+		log.Printf("failed to create order for flash sale %d user %d: %v", saleID, userID, err)
+		reason := status.Convert(err).Message()
+		if _, ok := status.FromError(err); !ok {
+			reason = "failed to create order"
+		}
+		if err := refundScript.Run(Ctx, RedisClient, keys, userID, flashSaleFailed+reason).Err(); err != nil {
+			log.Printf("failed to refund flash sale %d for user %d: %v", saleID, userID, err)
+		}
+		return
+	}
+	if err := RedisClient.HSet(Ctx, keys[3], strconv.FormatInt(userID, 10), fmt.Sprintf("%s%d", flashSaleSuccess, orderID)).Err(); err != nil {
+		log.Printf("failed to save flash sale %d result for user %d: %v", saleID, userID, err)
+	}
+}
+
+// createFlashSaleOrder 以秒杀价创建一件商品的订单。同一用户同一活动只会创建一个订单，消息重复投递时返回已有订单
+func (s *OrderService) createFlashSaleOrder(ctx context.Context, saleID uint, userID int64, lat, lng float64) (uint, error) {
+	var existing model.FlashSaleOrder
+	if err := s.db.Where("flash_sale_id = ? AND user_id = ?", saleID, userID).First(&existing).Error; err == nil {
+		return existing.OrderID, nil
+	}
+	var sale model.FlashSale
+	if err := s.db.First(&sale, saleID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, status.Error(codes.NotFound, "flash sale not found")
+		}
+		return 0, err
+	}
+
+	// 占用库存以订单服务的身份调用商品服务，不需要用户的 token
+	order := model.Order{
+		UserID:     userID,
+		TotalPrice: sale.Price,
+		Status:     int(pb.OrderStatus_PENDING),
+		Items: []model.OrderItem{{
+			ProductID:   sale.ProductID,
+			SkuID:       sale.SkuID,
+			SkuSpec:     sale.SkuSpec,
+			ProductName: sale.ProductName,
+			Quantity:    1,
+			Price:       sale.Price,
+		}},
+	}
+	req := &pb.CreateOrderRequest{UserId: userID, ShipToLatitude: lat, ShipToLongitude: lng}
+	err := s.saveOrder(ctx, &order, req, func(tx *gorm.DB) error {
+		return tx.Create(&model.FlashSaleOrder{FlashSaleID: saleID, UserID: userID, OrderID: order.ID}).Error
+	})
+	if err != nil {
+		// 并发重复处理时唯一索引冲突，以已创建的订单为准
+		if s.db.Where("flash_sale_id = ? AND user_id = ?", saleID, userID).First(&existing).Error == nil {
+			return existing.OrderID, nil
+		}
+		return 0, err
+	}
+	return order.ID, nil
+}
+
+func convertFlashSaleToPB(sale *model.FlashSale, remaining int) *pb.FlashSale {
+	return &pb.FlashSale{
+		Id:             int64(sale.ID),
+		ProductId:      sale.ProductID,
+		SkuId:          sale.SkuID,
+		ProductName:    sale.ProductName,
+		SkuSpec:        sale.SkuSpec,
+		Price:          sale.Price,
+		Stock:          int32(sale.Stock),
+		RemainingStock: int32(remaining),
+		StartAt:        sale.StartAt.Format(time.RFC3339),
+		EndAt:          sale.EndAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/order"
+	"context"
+	"order-service/model"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestRedis 将 RedisClient 指向内存中的 Redis，测试结束后恢复
+func newTestRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	prev := RedisClient
+	RedisClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		RedisClient.Close()
+		RedisClient = prev
+	})
+	return mr
+}
+
+// preloadTestFlashSale 预热一个从 start 开始、到 end 结束的活动
+func preloadTestFlashSale(t *testing.T, id uint, stock int, start, end time.Time) {
+	t.Helper()
+	sale := &model.FlashSale{Stock: stock, StartAt: start, EndAt: end}
+	sale.ID = id
+	if err := preloadFlashSale(context.Background(), sale); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurchaseScript(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		preload   bool
+		stock     int
+		start     time.Time
+		end       time.Time
+		buyers    []int64 // 之前已抢购的用户
+		wantCode  int
+		wantStock int
+	}{
+		{name: "success", preload: true, stock: 2, start: now.Add(-time.Minute), end: now.Add(time.Hour), wantCode: 1, wantStock: 1},
+		{name: "sold out", preload: true, stock: 1, start: now.Add(-time.Minute), end: now.Add(time.Hour), buyers: []int64{8}, wantCode: 0, wantStock: 0},
+		{name: "already purchased", preload: true, stock: 2, start: now.Add(-time.Minute), end: now.Add(time.Hour), buyers: []int64{7}, wantCode: -1, wantStock: 1},
+		{name: "not started", preload: true, stock: 2, start: now.Add(time.Minute), end: now.Add(time.Hour), wantCode: -2, wantStock: 2},
+		{name: "ended", preload: true, stock: 2, start: now.Add(-time.Hour), end: now.Add(-time.Minute), wantCode: -3, wantStock: 2},
+		{name: "not preloaded", wantCode: -4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := newTestRedis(t)
+			if tt.preload {
+				preloadTestFlashSale(t, 1, tt.stock, tt.start, tt.end)
+			}
+			keys := flashSaleKeys(1)
+			purchase := func(userID int64) int {
+				code, err := purchaseScript.Run(context.Background(), RedisClient, keys, userID, now.Unix(), flashSaleQueued).Int()
+				if err != nil {
+					t.Fatalf("purchase script error = %v", err)
+				}
+				return code
+			}
+			for _, buyer := range tt.buyers {
+				if code := purchase(buyer); code != 1 {
+					t.Fatalf("setup purchase for user %d = %d, want 1", buyer, code)
+				}
+			}
+			if code := purchase(7); code != tt.wantCode {
+				t.Errorf("purchase = %d, want %d", code, tt.wantCode)
+			}
+			if !tt.preload {
+				return
+			}
+			if stock, _ := mr.Get(flashSaleStockKey(1)); stock != strconv.Itoa(tt.wantStock) {
+				t.Errorf("stock = %s, want %d", stock, tt.wantStock)
+			}
+			if tt.wantCode == 1 {
+				if ok, _ := mr.SIsMember(flashSaleUsersKey(1), "7"); !ok {
+					t.Error("user 7 is not recorded as a buyer")
+				}
+				if result := mr.HGet(flashSaleResultsKey(1), "7"); result != flashSaleQueued {
+					t.Errorf("result = %q, want %q", result, flashSaleQueued)
+				}
+			}
+		})
+	}
+}
+
+func TestRefundScript(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     string
+		wantResult string
+	}{
+		{name: "clears result", result: ""},
+		{name: "records failure", result: flashSaleFailed + "stock not enough", wantResult: flashSaleFailed + "stock not enough"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := newTestRedis(t)
+			now := time.Now()
+			preloadTestFlashSale(t, 1, 1, now.Add(-time.Minute), now.Add(time.Hour))
+			keys := flashSaleKeys(1)
+			if code, err := purchaseScript.Run(context.Background(), RedisClient, keys, 7, now.Unix(), flashSaleQueued).Int(); err != nil || code != 1 {
+				t.Fatalf("purchase = %d, %v, want 1", code, err)
+			}
+			// 重复退回只退回一次库存
+			for i := 0; i < 2; i++ {
+				if err := refundScript.Run(context.Background(), RedisClient, keys, 7, tt.result).Err(); err != nil {
+					t.Fatalf("refund script error = %v", err)
+				}
+			}
+			if stock, _ := mr.Get(flashSaleStockKey(1)); stock != "1" {
+				t.Errorf("stock = %s, want 1", stock)
+			}
+			if ok, _ := mr.SIsMember(flashSaleUsersKey(1), "7"); ok {
+				t.Error("user 7 is still recorded as a buyer")
+			}
+			if mr.HGet(flashSaleResultsKey(1), "7") != tt.wantResult {
+				t.Errorf("result = %q, want %q", mr.HGet(flashSaleResultsKey(1), "7"), tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestPurchaseFlashSaleRefundsWhenQueueFails(t *testing.T) {
+	mr := newTestRedis(t)
+	now := time.Now()
+	preloadTestFlashSale(t, 1, 1, now.Add(-time.Minute), now.Add(time.Hour))
+	prevWriter := flashSaleWriter
+	flashSaleWriter = nil
+	t.Cleanup(func() { flashSaleWriter = prevWriter })
+
+	s := &OrderService{}
+	ctx := context.WithValue(context.Background(), "user", &middleware.Claims{UserID: 7})
+	_, err := s.PurchaseFlashSale(ctx, &pb.PurchaseFlashSaleRequest{FlashSaleId: 1})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("PurchaseFlashSale error = %v, want Unavailable", err)
+	}
+	if stock, _ := mr.Get(flashSaleStockKey(1)); stock != "1" {
+		t.Errorf("stock = %s, want 1", stock)
+	}
+	// 名额已退回，可以再次抢购
+	code, err := purchaseScript.Run(context.Background(), RedisClient, flashSaleKeys(1), 7, now.Unix(), flashSaleQueued).Int()
+	if err != nil || code != 1 {
+		t.Errorf("purchase after refund = %d, %v, want 1", code, err)
+	}
+}
+
+func TestCreateFlashSaleOrderDeduplicates(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Create(&model.FlashSaleOrder{FlashSaleID: 1, UserID: 7, OrderID: 99}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := &OrderService{db: db}
+	tests := []struct {
+		name      string
+		userID    int64
+		wantOrder uint
+		wantCode  codes.Code
+	}{
+		{name: "duplicate message returns existing order", userID: 7, wantOrder: 99, wantCode: codes.OK},
+		{name: "new user of missing sale", userID: 8, wantCode: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderID, err := s.createFlashSaleOrder(context.Background(), 1, tt.userID, 0, 0)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("createFlashSaleOrder error = %v, want %v", err, tt.wantCode)
+			}
+			if orderID != tt.wantOrder {
+				t.Errorf("order = %d, want %d", orderID, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestHandleFlashSaleMessageRefundsOnFailure(t *testing.T) {
+	mr := newTestRedis(t)
+	now := time.Now()
+	preloadTestFlashSale(t, 1, 1, now.Add(-time.Minute), now.Add(time.Hour))
+	if code, err := purchaseScript.Run(context.Background(), RedisClient, flashSaleKeys(1), 7, now.Unix(), flashSaleQueued).Int(); err != nil || code != 1 {
+		t.Fatalf("purchase = %d, %v, want 1", code, err)
+	}
+	// 数据库中没有该活动，创建订单失败
+	s := &OrderService{db: newTestDB(t)}
+	s.handleFlashSaleMessage("flash_sale_purchase|flash_sale_id=1|user_id=7|lat=0|lng=0")
+
+	if stock, _ := mr.Get(flashSaleStockKey(1)); stock != "1" {
+		t.Errorf("stock = %s, want 1", stock)
+	}
+	if want := flashSaleFailed + "flash sale not found"; mr.HGet(flashSaleResultsKey(1), "7") != want {
+		t.Errorf("result = %q, want %q", mr.HGet(flashSaleResultsKey(1), "7"), want)
+	}
+}
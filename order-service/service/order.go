@@ -3,8 +3,11 @@ package service
 import (
 	pb "common/proto/gen/order"
 	pbProduct "common/proto/gen/product"
+	pbUser "common/proto/gen/user"
 	"context"
 	"order-service/model"
+	"sort"
+	"strings"
 	"time"
 
 	"fmt"
@@ -18,16 +21,20 @@ type OrderService struct {
 	pb.UnimplementedOrderServiceServer
 	db            *gorm.DB
 	productClient pbProduct.ProductServiceClient
+	userClient    pbUser.UserServiceClient
 }
 
-func NewOrderService(db *gorm.DB, productClient pbProduct.ProductServiceClient) *OrderService {
-	return &OrderService{db: db, productClient: productClient}
+func NewOrderService(db *gorm.DB, productClient pbProduct.ProductServiceClient, userClient pbUser.UserServiceClient) *OrderService {
+	return &OrderService{db: db, productClient: productClient, userClient: userClient}
 }
 
 func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
-	// 1. 对每个商品加锁
+	// 1. 对每个商品加锁，有规格时按规格加锁
 	for _, item := range req.Items {
 		lockKey := fmt.Sprintf("lock:product:%d", item.ProductId)
+		if item.SkuId > 0 {
+			lockKey = fmt.Sprintf("lock:sku:%d", item.SkuId)
+		}
 		ok, err := RedisClient.SetNX(Ctx, lockKey, "locked", 5*time.Second).Result()
 		if err != nil || !ok {
 			return nil, status.Error(codes.Aborted, "order is too frequent, please try again later")
@@ -35,15 +42,53 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 		defer RedisClient.Del(Ctx, lockKey) // 下单结束后自动释放锁
 	}
 
-	// 1. 检查库存，调用商品服务
+	// 1. 检查库存，批量调用商品服务
+	productIDs := make([]int64, 0, len(req.Items))
 	for _, item := range req.Items {
-		resp, err := s.productClient.GetProduct(ctx, &pbProduct.GetProductRequest{ProductId: item.ProductId})
-		if err != nil {
-			return nil, status.Errorf(codes.FailedPrecondition, "failed to get product info: %v", err)
+		productIDs = append(productIDs, item.ProductId)
+	}
+	resp, err := s.productClient.BatchGetProducts(ctx, &pbProduct.BatchGetProductsRequest{ProductIds: productIDs})
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to get product info: %v", err)
+	}
+	// 未上架的商品对订单服务同样视为不存在
+	if len(resp.MissingIds) > 0 {
+		return nil, status.Errorf(codes.NotFound, "product %d not found or not available", resp.MissingIds[0])
+	}
+	products := make(map[int64]*pbProduct.Product, len(resp.Products))
+	for _, p := range resp.Products {
+		products[p.Id] = p
+	}
+	for _, item := range req.Items {
+		product, ok := products[item.ProductId]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "product %d not found", item.ProductId)
+		}
+		if product.Status != pbProduct.ProductStatus_PRODUCT_STATUS_ACTIVE {
+			return nil, status.Errorf(codes.FailedPrecondition, "product %s is not available", product.Name)
 		}
-		if resp.Product.Stock < item.Quantity {
-			return nil, status.Errorf(codes.FailedPrecondition, "product %s stock not enough", resp.Product.Name)
+		// 价格和名称以商品服务为准，忽略客户端传入的值
+		item.ProductName = product.Name
+		if len(product.Skus) == 0 {
+			if item.SkuId > 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "product %s has no sku", product.Name)
+			}
+			if product.Stock < item.Quantity {
+				return nil, status.Errorf(codes.FailedPrecondition, "product %s stock not enough", product.Name)
+			}
+			item.Price = product.Price
+			continue
 		}
+		// 多规格商品按规格检查库存，价格以规格价格为准
+		sku := findSku(product.Skus, item.SkuId)
+		if sku == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "product %s requires a valid sku_id", product.Name)
+		}
+		if sku.Stock < item.Quantity {
+			return nil, status.Errorf(codes.FailedPrecondition, "product %s (%s) stock not enough", product.Name, skuSpec(sku.Attributes))
+		}
+		item.Price = sku.Price
+		item.SkuSpec = skuSpec(sku.Attributes)
 	}
 	// 2. 计算总价
 	total := 0.0
@@ -59,34 +104,83 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 	for _, item := range req.Items {
 		order.Items = append(order.Items, model.OrderItem{
 			ProductID:   item.ProductId,
+			SkuID:       item.SkuId,
+			SkuSpec:     item.SkuSpec,
 			ProductName: item.ProductName,
 			Quantity:    int(item.Quantity),
 			Price:       item.Price,
 		})
 	}
-	if err := s.db.Create(&order).Error; err != nil {
+	// 4. 创建订单并占用库存
+	if err := s.saveOrder(ctx, &order, req, nil); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
 		return nil, status.Error(codes.Internal, "failed to create order")
 	}
-
-	// 发送 Kafka 消息
-	msg := fmt.Sprintf("order_created|order_id=%d|user_id=%d", order.ID, order.UserID)
-	err := SendOrderMessage(msg)
-	if err != nil {
-		fmt.Println("Failed to send Kafka message:", err)
-	}
 	return &pb.CreateOrderResponse{
 		OrderId: int64(order.ID),
 		Message: "order created successfully",
 	}, nil
 }
 
+// saveOrder 创建订单并占用库存，成功后发送订单创建消息。订单和库存占用要么都成功要么都失败：
+// 订单在事务中创建，占用库存失败时回滚；占用成功但事务提交失败时释放库存。
+// beforeReserve 不为空时在同一事务中、占用库存前执行
+func (s *OrderService) saveOrder(ctx context.Context, order *model.Order, req *pb.CreateOrderRequest, beforeReserve func(tx *gorm.DB) error) error {
+	reserved := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		if beforeReserve != nil {
+			if err := beforeReserve(tx); err != nil {
+				return err
+			}
+		}
+		if err := s.reserveStock(ctx, order, req); err != nil {
+			return err
+		}
+		reserved = true
+		for _, it := range order.Items {
+			if err := tx.Model(&model.OrderItem{}).Where("id = ?", it.ID).Update("allocations", it.Allocations).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if reserved {
+			s.releaseStock(ctx, order.ID)
+		}
+		return err
+	}
+
+	// 发送 Kafka 消息
+	msg := fmt.Sprintf("order_created|order_id=%d|user_id=%d|items=%s", order.ID, order.UserID, formatOrderItems(order.Items))
+	if err := SendOrderMessage(msg); err != nil {
+		fmt.Println("Failed to send Kafka message:", err)
+	}
+	return nil
+}
+
 func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
 	var order model.Order
 	if err := s.db.Preload("Items").First(&order, req.OrderId).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, status.Error(codes.NotFound, "order not found")
+		if err != gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.Internal, "failed to query order")
+		}
+		// 订单表中不存在时再查归档表
+		var archived model.ArchivedOrder
+		if err := s.db.Preload("Items").First(&archived, req.OrderId).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, status.Error(codes.NotFound, "order not found")
+			}
+			return nil, status.Error(codes.Internal, "failed to query order")
 		}
-		return nil, status.Error(codes.Internal, "failed to query order")
+		return &pb.GetOrderResponse{
+			Order: convertArchivedOrderToPB(&archived),
+		}, nil
 	}
 	return &pb.GetOrderResponse{
 		Order: convertOrderModelToPB(&order),
@@ -94,66 +188,85 @@ func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*
 }
 
 func (s *OrderService) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
-	var orders []model.Order
-	var total int64
-	page := int(req.Page)
 	pageSize := int(req.PageSize)
-	if page <= 0 {
-		page = 1
-	}
 	if pageSize <= 0 {
 		pageSize = 10
 	}
-	offset := (page - 1) * pageSize
-	dbQuery := s.db.Model(&model.Order{}).Where("user_id = ?", req.UserId)
-	if err := dbQuery.Count(&total).Error; err != nil {
-		return nil, status.Error(codes.Internal, "failed to count orders")
+	// 传入游标时使用键集分页；仅在旧客户端指定 page 时退化为偏移分页
+	var cursor *orderCursor
+	offset := 0
+	if req.Cursor != "" {
+		c, err := decodeOrderCursor(req.Cursor, req.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		cursor = c
+	} else if req.Page > 1 {
+		offset = (int(req.Page) - 1) * pageSize
+	}
+
+	// 多取一条用于判断是否还有下一页
+	filter := listOrdersFilter(req)
+	pbOrders, err := s.queryOrders(filter, req.IncludeArchived, req.SortBy, cursor, offset, pageSize+1)
+	if err != nil {
+		return nil, err
 	}
-	if err := dbQuery.Preload("Items").Limit(pageSize).Offset(offset).Order("id desc").Find(&orders).Error; err != nil {
-		return nil, status.Error(codes.Internal, "failed to list orders")
+	resp := &pb.ListOrdersResponse{}
+	if len(pbOrders) > pageSize {
+		pbOrders = pbOrders[:pageSize]
+		resp.HasMore = true
+		resp.NextCursor = encodeOrderCursor(pbOrders[pageSize-1], req.SortBy)
 	}
-	var pbOrders []*pb.Order
-	for _, o := range orders {
-		pbOrders = append(pbOrders, convertOrderModelToPB(&o))
+	resp.Orders = pbOrders
+
+	if req.WithTotal || req.Page > 0 {
+		total, err := s.countOrders(filter, req.IncludeArchived)
+		if err != nil {
+			return nil, err
+		}
+		resp.Total = int32(total)
 	}
-	return &pb.ListOrdersResponse{
-		Orders: pbOrders,
-		Total:  int32(total),
-	}, nil
+	return resp, nil
 }
 
 func (s *OrderService) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrderStatusRequest) (*pb.UpdateOrderStatusResponse, error) {
 	var order model.Order
-	if err := s.db.First(&order, req.OrderId).Error; err != nil {
+	if err := s.db.Preload("Items").First(&order, req.OrderId).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return &pb.UpdateOrderStatusResponse{Success: false, Message: "order not found"}, nil
 		}
 		return &pb.UpdateOrderStatusResponse{Success: false, Message: "failed to query order"}, nil
 	}
+	canceled := order.Status != int(pb.OrderStatus_CANCELED) && req.Status == pb.OrderStatus_CANCELED
+	// 记录完成时间，订单离开已完成状态时清除
+	if req.Status == pb.OrderStatus_COMPLETED {
+		if order.Status != int(pb.OrderStatus_COMPLETED) {
+			now := time.Now()
+			order.CompletedAt = &now
+		}
+	} else {
+		order.CompletedAt = nil
+	}
 	order.Status = int(req.Status)
-	if err := s.db.Save(&order).Error; err != nil {
+	if err := s.db.Omit("Items").Save(&order).Error; err != nil {
 		return &pb.UpdateOrderStatusResponse{Success: false, Message: "failed to update status"}, nil
 	}
+	if canceled {
+		msg := fmt.Sprintf("order_canceled|order_id=%d|user_id=%d|items=%s", order.ID, order.UserID, formatOrderItems(order.Items))
+		if err := SendOrderMessage(msg); err != nil {
+			fmt.Println("Failed to send Kafka message:", err)
+		}
+	}
 	return &pb.UpdateOrderStatusResponse{Success: true, Message: "order status updated"}, nil
 }
 
+// DeleteOrder 已废弃，不再物理删除订单，改为用户隐藏
 func (s *OrderService) DeleteOrder(ctx context.Context, req *pb.DeleteOrderRequest) (*pb.DeleteOrderResponse, error) {
-	// 先查找订单是否存在
-	var order model.Order
-	if err := s.db.First(&order, req.OrderId).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return &pb.DeleteOrderResponse{Success: false, Message: "order not found"}, nil
-		}
-		return &pb.DeleteOrderResponse{Success: false, Message: "failed to query order"}, nil
-	}
-	// 先删除订单项，再删订单
-	if err := s.db.Where("order_id = ?", req.OrderId).Delete(&model.OrderItem{}).Error; err != nil {
-		return &pb.DeleteOrderResponse{Success: false, Message: "failed to delete order items"}, nil
-	}
-	if err := s.db.Delete(&model.Order{}, req.OrderId).Error; err != nil {
-		return &pb.DeleteOrderResponse{Success: false, Message: "failed to delete order"}, nil
+	resp, err := s.HideOrder(ctx, &pb.HideOrderRequest{OrderId: req.OrderId})
+	if err != nil {
+		return nil, err
 	}
-	return &pb.DeleteOrderResponse{Success: true, Message: "order deleted successfully"}, nil
+	return &pb.DeleteOrderResponse{Success: resp.Success, Message: resp.Message}, nil
 }
 
 // 工具函数：模型转pb
@@ -161,10 +274,14 @@ func convertOrderModelToPB(order *model.Order) *pb.Order {
 	var items []*pb.OrderItem
 	for _, it := range order.Items {
 		items = append(items, &pb.OrderItem{
+			Id:          int64(it.ID),
 			ProductId:   it.ProductID,
+			SkuId:       it.SkuID,
+			SkuSpec:     it.SkuSpec,
 			ProductName: it.ProductName,
 			Quantity:    int32(it.Quantity),
 			Price:       it.Price,
+			Allocations: convertAllocationsToPB(it.AllocationList()),
 		})
 	}
 	return &pb.Order{
@@ -177,3 +294,49 @@ func convertOrderModelToPB(order *model.Order) *pb.Order {
 		UpdatedAt:  order.UpdatedAt.Format(time.RFC3339),
 	}
 }
+
+func convertArchivedOrderToPB(archived *model.ArchivedOrder) *pb.Order {
+	order := archived.ToOrder()
+	pbOrder := convertOrderModelToPB(&order)
+	pbOrder.Archived = true
+	return pbOrder
+}
+
+// formatOrderItems 将订单项编码为 "商品ID:数量[:规格ID]" 列表，如 "101:2,102:1:55"
+func formatOrderItems(items []model.OrderItem) string {
+	parts := make([]string, 0, len(items))
+	for _, it := range items {
+		if it.SkuID > 0 {
+			parts = append(parts, fmt.Sprintf("%d:%d:%d", it.ProductID, it.Quantity, it.SkuID))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d:%d", it.ProductID, it.Quantity))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func findSku(skus []*pbProduct.Sku, skuID int64) *pbProduct.Sku {
+	if skuID <= 0 {
+		return nil
+	}
+	for _, sku := range skus {
+		if sku.Id == skuID {
+			return sku
+		}
+	}
+	return nil
+}
+
+// skuSpec 规格描述，按属性名排序，与商品服务保持一致
+func skuSpec(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+":"+attrs[k])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/order"
+	"context"
+	"fmt"
+	"log"
+	"order-service/model"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultArchiveDays  = 180
+	defaultArchiveLimit = 500
+)
+
+// HideOrder 用户隐藏订单，订单数据保留，只是不再出现在用户的订单列表中
+func (s *OrderService) HideOrder(ctx context.Context, req *pb.HideOrderRequest) (*pb.HideOrderResponse, error) {
+	var order model.Order
+	if err := s.db.First(&order, req.OrderId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.HideOrderResponse{Success: false, Message: "order not found"}, nil
+		}
+		return &pb.HideOrderResponse{Success: false, Message: "failed to query order"}, nil
+	}
+	if err := checkOrderOwner(ctx, order.UserID); err != nil {
+		return nil, err
+	}
+	// 只有已完成或已取消的订单才能隐藏
+	if order.Status != int(pb.OrderStatus_COMPLETED) && order.Status != int(pb.OrderStatus_CANCELED) {
+		return &pb.HideOrderResponse{Success: false, Message: "only completed or canceled orders can be hidden"}, nil
+	}
+	if order.HiddenAt != nil {
+		return &pb.HideOrderResponse{Success: true, Message: "order already hidden"}, nil
+	}
+	if err := s.db.Model(&order).Update("hidden_at", time.Now()).Error; err != nil {
+		return &pb.HideOrderResponse{Success: false, Message: "failed to hide order"}, nil
+	}
+	return &pb.HideOrderResponse{Success: true, Message: "order hidden successfully"}, nil
+}
+
+// ArchiveOrders 将完成时间较早的订单移动到归档表，仅管理员可调用
+func (s *OrderService) ArchiveOrders(ctx context.Context, req *pb.ArchiveOrdersRequest) (*pb.ArchiveOrdersResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	days := int(req.OlderThanDays)
+	if days <= 0 {
+		days = defaultArchiveDays
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultArchiveLimit
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	var orders []model.Order
+	if err := s.db.Preload("Items").
+		Where("status = ? AND completed_at < ?", int(pb.OrderStatus_COMPLETED), cutoff).
+		Order("id asc").Limit(limit).Find(&orders).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query orders")
+	}
+
+	// 逐个订单归档，单个订单失败时记录下来继续归档其余订单
+	now := time.Now()
+	resp := &pb.ArchiveOrdersResponse{}
+	for i := range orders {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			record := model.NewArchivedOrder(&orders[i], now)
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("order_id = ?", orders[i].ID).Delete(&model.OrderItem{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&model.Order{}, orders[i].ID).Error
+		})
+		if err != nil {
+			log.Printf("Failed to archive order %d: %v", orders[i].ID, err)
+			resp.Failed++
+			resp.FailedOrderIds = append(resp.FailedOrderIds, int64(orders[i].ID))
+			continue
+		}
+		resp.Archived++
+	}
+	resp.Message = "orders archived successfully"
+	if resp.Failed > 0 {
+		resp.Message = fmt.Sprintf("%d orders archived, %d failed", resp.Archived, resp.Failed)
+	}
+	return resp, nil
+}
+
+// RestoreOrder 将归档订单移回订单表，仅管理员可调用
+func (s *OrderService) RestoreOrder(ctx context.Context, req *pb.RestoreOrderRequest) (*pb.RestoreOrderResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var archived model.ArchivedOrder
+	if err := s.db.Preload("Items").First(&archived, req.OrderId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.RestoreOrderResponse{Success: false, Message: "archived order not found"}, nil
+		}
+		return &pb.RestoreOrderResponse{Success: false, Message: "failed to query archived order"}, nil
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		order := archived.ToOrder()
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", archived.ID).Delete(&model.ArchivedOrderItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.ArchivedOrder{}, archived.ID).Error
+	})
+	if err != nil {
+		return &pb.RestoreOrderResponse{Success: false, Message: "failed to restore order"}, nil
+	}
+	return &pb.RestoreOrderResponse{Success: true, Message: "order restored successfully"}, nil
+}
+
+// checkOrderOwner 校验当前用户是否为订单所有者，管理员不受限制
+func checkOrderOwner(ctx context.Context, userID int64) error {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	if claims.Role == middleware.RoleAdmin || claims.UserID == userID {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "order does not belong to current user")
+}
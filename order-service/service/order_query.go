@@ -0,0 +1,250 @@
+package service
+
+import (
+	pb "common/proto/gen/order"
+	"encoding/base64"
+	"encoding/json"
+	"order-service/model"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// orderCursor 游标分页位置，编码后对客户端不透明
+// 游标记录生成时的排序字段和方向，换了排序方式后不能继续使用
+type orderCursor struct {
+	Field string  `json:"f"`
+	Dir   string  `json:"d"`
+	Total float64 `json:"t,omitempty"`
+	ID    int64   `json:"id"`
+}
+
+// orderSortKey 返回排序方式对应的排序字段和方向
+func orderSortKey(sortBy pb.OrderSortBy) (field, dir string) {
+	switch sortBy {
+	case pb.OrderSortBy_ORDER_SORT_CREATED_ASC:
+		return "created", "asc"
+	case pb.OrderSortBy_ORDER_SORT_TOTAL_DESC:
+		return "total", "desc"
+	case pb.OrderSortBy_ORDER_SORT_TOTAL_ASC:
+		return "total", "asc"
+	default:
+		return "created", "desc"
+	}
+}
+
+func encodeOrderCursor(order *pb.Order, sortBy pb.OrderSortBy) string {
+	field, dir := orderSortKey(sortBy)
+	bytes, _ := json.Marshal(orderCursor{Field: field, Dir: dir, Total: order.TotalPrice, ID: order.Id})
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// decodeOrderCursor 解析游标，游标的排序方式与本次请求不一致时返回 InvalidArgument
+func decodeOrderCursor(cursor string, sortBy pb.OrderSortBy) (*orderCursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+	var c orderCursor
+	if err := json.Unmarshal(bytes, &c); err != nil || c.ID <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+	if field, dir := orderSortKey(sortBy); c.Field != field || c.Dir != dir {
+		return nil, status.Error(codes.InvalidArgument, "cursor does not match sort order")
+	}
+	return &c, nil
+}
+
+// orderFilter 订单查询条件，订单列表、搜索与导出共用
+type orderFilter struct {
+	UserIDs       []int64 // 为空表示不限用户
+	ExcludeHidden bool    // 是否排除用户隐藏的订单
+	OrderID       int64
+	Statuses      []pb.OrderStatus
+	CreatedFrom   string
+	CreatedTo     string
+	MinTotal      float64
+	MaxTotal      float64
+	ProductID     int64
+	ProductName   string
+}
+
+func listOrdersFilter(req *pb.ListOrdersRequest) *orderFilter {
+	return &orderFilter{
+		UserIDs:       []int64{req.UserId},
+		ExcludeHidden: true,
+		Statuses:      req.Statuses,
+		CreatedFrom:   req.CreatedFrom,
+		CreatedTo:     req.CreatedTo,
+		MinTotal:      req.MinTotal,
+		MaxTotal:      req.MaxTotal,
+		ProductID:     req.ProductId,
+	}
+}
+
+// applyOrderFilters 按条件过滤订单，订单表与归档表共用，itemModel 为对应的订单项模型
+func applyOrderFilters(db, q *gorm.DB, f *orderFilter, itemModel interface{}) (*gorm.DB, error) {
+	if len(f.UserIDs) == 1 {
+		q = q.Where("user_id = ?", f.UserIDs[0])
+	} else if len(f.UserIDs) > 1 {
+		q = q.Where("user_id IN ?", f.UserIDs)
+	}
+	if f.ExcludeHidden {
+		q = q.Where("hidden_at IS NULL")
+	}
+	if f.OrderID > 0 {
+		q = q.Where("id = ?", f.OrderID)
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]int, 0, len(f.Statuses))
+		for _, st := range f.Statuses {
+			statuses = append(statuses, int(st))
+		}
+		q = q.Where("status IN ?", statuses)
+	}
+	if f.CreatedFrom != "" {
+		from, err := time.Parse(time.RFC3339, f.CreatedFrom)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid created_from")
+		}
+		q = q.Where("created_at >= ?", from)
+	}
+	if f.CreatedTo != "" {
+		to, err := time.Parse(time.RFC3339, f.CreatedTo)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid created_to")
+		}
+		q = q.Where("created_at < ?", to)
+	}
+	if f.MinTotal > 0 {
+		q = q.Where("total_price >= ?", f.MinTotal)
+	}
+	if f.MaxTotal > 0 {
+		q = q.Where("total_price <= ?", f.MaxTotal)
+	}
+	if f.ProductID > 0 {
+		q = q.Where("id IN (?)", db.Model(itemModel).Select("order_id").Where("product_id = ?", f.ProductID))
+	}
+	if f.ProductName != "" {
+		q = q.Where("id IN (?)", db.Model(itemModel).Select("order_id").Where("product_name LIKE ?", "%"+f.ProductName+"%"))
+	}
+	return q, nil
+}
+
+// applyOrderCursor 追加排序以及游标之后的键集条件
+func applyOrderCursor(q *gorm.DB, sortBy pb.OrderSortBy, cursor *orderCursor) *gorm.DB {
+	switch sortBy {
+	case pb.OrderSortBy_ORDER_SORT_CREATED_ASC:
+		if cursor != nil {
+			q = q.Where("id > ?", cursor.ID)
+		}
+		return q.Order("id asc")
+	case pb.OrderSortBy_ORDER_SORT_TOTAL_DESC:
+		if cursor != nil {
+			q = q.Where("total_price < ? OR (total_price = ? AND id < ?)", cursor.Total, cursor.Total, cursor.ID)
+		}
+		return q.Order("total_price desc").Order("id desc")
+	case pb.OrderSortBy_ORDER_SORT_TOTAL_ASC:
+		if cursor != nil {
+			q = q.Where("total_price > ? OR (total_price = ? AND id > ?)", cursor.Total, cursor.Total, cursor.ID)
+		}
+		return q.Order("total_price asc").Order("id asc")
+	default:
+		// 订单 ID 自增，按 ID 排序即按创建时间排序
+		if cursor != nil {
+			q = q.Where("id < ?", cursor.ID)
+		}
+		return q.Order("id desc")
+	}
+}
+
+// sortOrders 按与 applyOrderCursor 一致的规则对合并后的订单排序
+func sortOrders(orders []*pb.Order, sortBy pb.OrderSortBy) {
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		switch sortBy {
+		case pb.OrderSortBy_ORDER_SORT_CREATED_ASC:
+			return a.Id < b.Id
+		case pb.OrderSortBy_ORDER_SORT_TOTAL_DESC:
+			if a.TotalPrice != b.TotalPrice {
+				return a.TotalPrice > b.TotalPrice
+			}
+			return a.Id > b.Id
+		case pb.OrderSortBy_ORDER_SORT_TOTAL_ASC:
+			if a.TotalPrice != b.TotalPrice {
+				return a.TotalPrice < b.TotalPrice
+			}
+			return a.Id < b.Id
+		default:
+			return a.Id > b.Id
+		}
+	})
+}
+
+// queryOrders 查询订单表（以及可选的归档表），返回排序后的前 offset+limit 条
+func (s *OrderService) queryOrders(f *orderFilter, includeArchived bool, sortBy pb.OrderSortBy, cursor *orderCursor, offset, limit int) ([]*pb.Order, error) {
+	live, err := applyOrderFilters(s.db, s.db.Model(&model.Order{}), f, &model.OrderItem{})
+	if err != nil {
+		return nil, err
+	}
+	live = applyOrderCursor(live, sortBy, cursor)
+	if !includeArchived {
+		var orders []model.Order
+		if err := live.Preload("Items").Offset(offset).Limit(limit).Find(&orders).Error; err != nil {
+			return nil, status.Error(codes.Internal, "failed to list orders")
+		}
+		var pbOrders []*pb.Order
+		for _, o := range orders {
+			pbOrders = append(pbOrders, convertOrderModelToPB(&o))
+		}
+		return pbOrders, nil
+	}
+
+	// 两张表各取前 offset+limit 条即可覆盖合并后的目标范围
+	var orders []model.Order
+	if err := live.Preload("Items").Limit(offset + limit).Find(&orders).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to list orders")
+	}
+	archivedQuery, _ := applyOrderFilters(s.db, s.db.Model(&model.ArchivedOrder{}), f, &model.ArchivedOrderItem{})
+	archivedQuery = applyOrderCursor(archivedQuery, sortBy, cursor)
+	var archivedOrders []model.ArchivedOrder
+	if err := archivedQuery.Preload("Items").Limit(offset + limit).Find(&archivedOrders).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to list archived orders")
+	}
+	var pbOrders []*pb.Order
+	for _, o := range orders {
+		pbOrders = append(pbOrders, convertOrderModelToPB(&o))
+	}
+	for _, o := range archivedOrders {
+		pbOrders = append(pbOrders, convertArchivedOrderToPB(&o))
+	}
+	sortOrders(pbOrders, sortBy)
+	if offset >= len(pbOrders) {
+		return nil, nil
+	}
+	return pbOrders[offset:min(offset+limit, len(pbOrders))], nil
+}
+
+// countOrders 统计满足过滤条件的订单总数
+func (s *OrderService) countOrders(f *orderFilter, includeArchived bool) (int64, error) {
+	var total int64
+	live, err := applyOrderFilters(s.db, s.db.Model(&model.Order{}), f, &model.OrderItem{})
+	if err != nil {
+		return 0, err
+	}
+	if err := live.Count(&total).Error; err != nil {
+		return 0, status.Error(codes.Internal, "failed to count orders")
+	}
+	if includeArchived {
+		var archivedTotal int64
+		archivedQuery, _ := applyOrderFilters(s.db, s.db.Model(&model.ArchivedOrder{}), f, &model.ArchivedOrderItem{})
+		if err := archivedQuery.Count(&archivedTotal).Error; err != nil {
+			return 0, status.Error(codes.Internal, "failed to count orders")
+		}
+		total += archivedTotal
+	}
+	return total, nil
+}
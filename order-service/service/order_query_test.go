@@ -0,0 +1,184 @@
+package service
+
+import (
+	pb "common/proto/gen/order"
+	"context"
+	"order-service/model"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 内存中的 SQLite 数据库，每个测试独立
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库只存在于单个连接中
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.Order{}, &model.OrderItem{}, &model.ArchivedOrder{}, &model.ArchivedOrderItem{},
+		&model.FlashSale{}, &model.FlashSaleOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestOrderCursorRoundTrip(t *testing.T) {
+	sorts := []pb.OrderSortBy{
+		pb.OrderSortBy_ORDER_SORT_CREATED_DESC,
+		pb.OrderSortBy_ORDER_SORT_CREATED_ASC,
+		pb.OrderSortBy_ORDER_SORT_TOTAL_DESC,
+		pb.OrderSortBy_ORDER_SORT_TOTAL_ASC,
+	}
+	order := &pb.Order{Id: 42, TotalPrice: 99.5}
+	for _, encodeSort := range sorts {
+		for _, decodeSort := range sorts {
+			t.Run(encodeSort.String()+"/"+decodeSort.String(), func(t *testing.T) {
+				cursor, err := decodeOrderCursor(encodeOrderCursor(order, encodeSort), decodeSort)
+				if encodeSort != decodeSort {
+					if status.Code(err) != codes.InvalidArgument {
+						t.Fatalf("decode error = %v, want InvalidArgument", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("decode error = %v", err)
+				}
+				if cursor.ID != order.Id || cursor.Total != order.TotalPrice {
+					t.Errorf("cursor = %+v, want id %d total %v", cursor, order.Id, order.TotalPrice)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeOrderCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!!!"},
+		{name: "not json", cursor: "bm90LWpzb24"},
+		{name: "missing id", cursor: "eyJmIjoiY3JlYXRlZCIsImQiOiJkZXNjIn0"},
+		{name: "missing sort", cursor: "eyJpZCI6NDJ9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeOrderCursor(tt.cursor, pb.OrderSortBy_ORDER_SORT_CREATED_DESC)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("decode error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestListOrdersMergesArchivedOrders(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	// 订单 1、3、4 在订单表中，2、5 已归档；订单 6 属于其他用户
+	live := []model.Order{
+		{Model: gorm.Model{ID: 1}, UserID: 1, TotalPrice: 30},
+		{Model: gorm.Model{ID: 3}, UserID: 1, TotalPrice: 10},
+		{Model: gorm.Model{ID: 4}, UserID: 1, TotalPrice: 20},
+		{Model: gorm.Model{ID: 6}, UserID: 2, TotalPrice: 50},
+	}
+	archived := []model.ArchivedOrder{
+		{ID: 2, UserID: 1, TotalPrice: 20, ArchivedAt: now},
+		{ID: 5, UserID: 1, TotalPrice: 40, ArchivedAt: now},
+	}
+	if err := db.Create(&live).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&archived).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := &OrderService{db: db}
+
+	tests := []struct {
+		name            string
+		sortBy          pb.OrderSortBy
+		includeArchived bool
+		want            []int64
+	}{
+		{name: "created desc", sortBy: pb.OrderSortBy_ORDER_SORT_CREATED_DESC, includeArchived: true, want: []int64{5, 4, 3, 2, 1}},
+		{name: "created asc", sortBy: pb.OrderSortBy_ORDER_SORT_CREATED_ASC, includeArchived: true, want: []int64{1, 2, 3, 4, 5}},
+		{name: "total desc breaks ties by id", sortBy: pb.OrderSortBy_ORDER_SORT_TOTAL_DESC, includeArchived: true, want: []int64{5, 1, 4, 2, 3}},
+		{name: "total asc breaks ties by id", sortBy: pb.OrderSortBy_ORDER_SORT_TOTAL_ASC, includeArchived: true, want: []int64{3, 2, 4, 1, 5}},
+		{name: "live only", sortBy: pb.OrderSortBy_ORDER_SORT_CREATED_DESC, want: []int64{4, 3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			cursor := ""
+			for page := 0; page < 10; page++ {
+				resp, err := s.ListOrders(context.Background(), &pb.ListOrdersRequest{
+					UserId:          1,
+					SortBy:          tt.sortBy,
+					IncludeArchived: tt.includeArchived,
+					PageSize:        2,
+					Cursor:          cursor,
+				})
+				if err != nil {
+					t.Fatalf("ListOrders error = %v", err)
+				}
+				for _, o := range resp.Orders {
+					got = append(got, o.Id)
+					if wantArchived := o.Id == 2 || o.Id == 5; o.Archived != wantArchived {
+						t.Errorf("order %d archived = %v, want %v", o.Id, o.Archived, wantArchived)
+					}
+				}
+				if !resp.HasMore {
+					break
+				}
+				cursor = resp.NextCursor
+			}
+			if !equalIDs(got, tt.want) {
+				t.Errorf("orders = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("cursor from another sort order", func(t *testing.T) {
+		resp, err := s.ListOrders(context.Background(), &pb.ListOrdersRequest{
+			UserId:   1,
+			SortBy:   pb.OrderSortBy_ORDER_SORT_TOTAL_DESC,
+			PageSize: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.ListOrders(context.Background(), &pb.ListOrdersRequest{
+			UserId:   1,
+			SortBy:   pb.OrderSortBy_ORDER_SORT_CREATED_DESC,
+			PageSize: 1,
+			Cursor:   resp.NextCursor,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("ListOrders error = %v, want InvalidArgument", err)
+		}
+	})
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/order"
+	pbUser "common/proto/gen/user"
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxSearchPageSize = 100
+
+// SearchOrders 跨用户搜索订单并附带用户信息，仅客服和管理员可调用
+func (s *OrderService) SearchOrders(ctx context.Context, req *pb.SearchOrdersRequest) (*pb.SearchOrdersResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin, middleware.RoleSupport); err != nil {
+		return nil, err
+	}
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	if pageSize > maxSearchPageSize {
+		pageSize = maxSearchPageSize
+	}
+	var cursor *orderCursor
+	if req.Cursor != "" {
+		c, err := decodeOrderCursor(req.Cursor, pb.OrderSortBy_ORDER_SORT_CREATED_DESC)
+		if err != nil {
+			return nil, err
+		}
+		cursor = c
+	}
+
+	filter := &orderFilter{
+		OrderID:     req.OrderId,
+		Statuses:    req.Statuses,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		ProductName: req.ProductName,
+	}
+	if req.UserId > 0 {
+		filter.UserIDs = []int64{req.UserId}
+	}
+	// 按用户名或邮箱搜索时先到用户服务查出匹配的用户ID
+	if req.Username != "" {
+		resp, err := s.userClient.LookupUsers(middleware.ForwardAuth(ctx), &pbUser.LookupUsersRequest{
+			UserIds:  filter.UserIDs,
+			Username: req.Username,
+		})
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to lookup users: %v", err)
+		}
+		// 匹配的用户过多时按截断后的用户搜索会漏掉订单，要求调用方缩小范围
+		if resp.Truncated {
+			return nil, status.Error(codes.InvalidArgument, "too many users match username, please refine the search")
+		}
+		if len(resp.Users) == 0 {
+			return &pb.SearchOrdersResponse{}, nil
+		}
+		filter.UserIDs = nil
+		for _, u := range resp.Users {
+			filter.UserIDs = append(filter.UserIDs, u.UserId)
+		}
+	}
+
+	pbOrders, err := s.queryOrders(filter, req.IncludeArchived, pb.OrderSortBy_ORDER_SORT_CREATED_DESC, cursor, 0, pageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.SearchOrdersResponse{}
+	if len(pbOrders) > pageSize {
+		pbOrders = pbOrders[:pageSize]
+		resp.HasMore = true
+		resp.NextCursor = encodeOrderCursor(pbOrders[pageSize-1], pb.OrderSortBy_ORDER_SORT_CREATED_DESC)
+	}
+	users := s.lookupUsers(ctx, pbOrders)
+	for _, o := range pbOrders {
+		hit := &pb.OrderSearchHit{Order: o}
+		if u, ok := users[o.UserId]; ok {
+			hit.Username = u.Username
+			hit.Email = u.Email
+		}
+		resp.Hits = append(resp.Hits, hit)
+	}
+	return resp, nil
+}
+
+// lookupUsers 批量查询订单对应的用户信息，用户服务不可用时返回空结果，不影响搜索
+func (s *OrderService) lookupUsers(ctx context.Context, orders []*pb.Order) map[int64]*pbUser.UserInfo {
+	users := make(map[int64]*pbUser.UserInfo)
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, o := range orders {
+		if !seen[o.UserId] {
+			seen[o.UserId] = true
+			ids = append(ids, o.UserId)
+		}
+	}
+	if len(ids) == 0 {
+		return users
+	}
+	resp, err := s.userClient.LookupUsers(middleware.ForwardAuth(ctx), &pbUser.LookupUsersRequest{
+		UserIds: ids,
+		Limit:   int32(len(ids)),
+	})
+	if err != nil {
+		return users
+	}
+	for _, u := range resp.Users {
+		users[u.UserId] = u
+	}
+	return users
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/order"
+	pbProduct "common/proto/gen/product"
+	"context"
+	"log"
+	"order-service/model"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultRecommendationInterval 共同购买统计的默认计算间隔
+	DefaultRecommendationInterval = time.Hour
+	// recommendationLookbackDays 只统计最近这些天的订单
+	recommendationLookbackDays = 180
+	// recommendationHistorySize 按用户最近购买的这些商品计算推荐
+	recommendationHistorySize  = 20
+	defaultRecommendationLimit = 10
+	maxRecommendationLimit     = 50
+	// recommendationLockKey 多个实例同时运行时只有一个实例计算
+	recommendationLockKey = "lock:recommendations"
+	recommendationLockTTL = 10 * time.Minute
+)
+
+// StartRecommendationJob 启动共同购买统计的定时计算任务
+func (s *OrderService) StartRecommendationJob(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRecommendationInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.computeCoPurchases(time.Now()); err != nil {
+				log.Printf("Failed to compute co-purchases: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// computeCoPurchases 按最近未取消的订单重新计算共同购买统计。统计在数据库中完成并逐对更新，
+// 本次没有更新到的商品对随后删除，计算过程中读取方始终能查到数据
+func (s *OrderService) computeCoPurchases(now time.Time) error {
+	ok, err := RedisClient.SetNX(Ctx, recommendationLockKey, "locked", recommendationLockTTL).Result()
+	if err != nil || !ok {
+		return err
+	}
+	defer RedisClient.Del(Ctx, recommendationLockKey)
+
+	// 以秒为单位记录本次计算的时间，保证写入后与删除条件中的值一致
+	runAt := now.Truncate(time.Second)
+	err = s.db.Exec(`INSERT INTO co_purchases (product_id, related_id, order_count, updated_at)
+		SELECT a.product_id, b.product_id, COUNT(DISTINCT a.order_id), ?
+		FROM order_items AS a
+		JOIN order_items AS b ON b.order_id = a.order_id AND b.product_id <> a.product_id AND b.deleted_at IS NULL
+		JOIN orders AS o ON o.id = a.order_id AND o.deleted_at IS NULL
+		WHERE a.deleted_at IS NULL AND o.status <> ? AND o.created_at >= ?
+		GROUP BY a.product_id, b.product_id
+		ON DUPLICATE KEY UPDATE order_count = VALUES(order_count), updated_at = VALUES(updated_at)`,
+		runAt, int(pb.OrderStatus_CANCELED), now.AddDate(0, 0, -recommendationLookbackDays)).Error
+	if err != nil {
+		return err
+	}
+	// 不再出现的商品对（订单取消或超出统计范围）
+	return s.db.Where("updated_at < ?", runAt).Delete(&model.CoPurchase{}).Error
+}
+
+// GetRelatedProducts 经常与该商品一起购买的商品，按共同购买的订单数排序
+func (s *OrderService) GetRelatedProducts(ctx context.Context, req *pb.GetRelatedProductsRequest) (*pb.GetRelatedProductsResponse, error) {
+	if req.ProductId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	limit := recommendationLimit(req.Limit)
+	// 多取一些，过滤掉下架和无库存的商品后仍然够数
+	var related []model.CoPurchase
+	if err := s.db.Where("product_id = ?", req.ProductId).
+		Order("order_count desc, related_id asc").Limit(limit * 3).Find(&related).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query related products")
+	}
+	ids := make([]int64, 0, len(related))
+	scores := make(map[int64]int, len(related))
+	for _, r := range related {
+		ids = append(ids, r.RelatedID)
+		scores[r.RelatedID] = r.OrderCount
+	}
+	products, err := s.availableProducts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetRelatedProductsResponse{}
+	for _, p := range products {
+		if len(resp.Products) == limit {
+			break
+		}
+		resp.Products = append(resp.Products, convertRecommendedProduct(p, scores[p.Id]))
+	}
+	return resp, nil
+}
+
+// GetRecommendationsForUser 按用户最近购买的商品汇总共同购买统计，排除已购买的商品，不足时补充热销商品
+func (s *OrderService) GetRecommendationsForUser(ctx context.Context, req *pb.GetRecommendationsForUserRequest) (*pb.GetRecommendationsForUserResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	userID := req.UserId
+	if userID == 0 {
+		userID = claims.UserID
+	}
+	if userID != claims.UserID && claims.Role != middleware.RoleAdmin {
+		return nil, status.Error(codes.PermissionDenied, "cannot view recommendations of other users")
+	}
+	limit := recommendationLimit(req.Limit)
+
+	var purchased []int64
+	if err := s.db.Model(&model.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Where("orders.user_id = ? AND orders.status <> ?", userID, int(pb.OrderStatus_CANCELED)).
+		Group("order_items.product_id").Order("MAX(order_items.id) desc").
+		Limit(recommendationHistorySize).Pluck("order_items.product_id", &purchased).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query purchase history")
+	}
+	excluded := make(map[int64]bool, len(purchased))
+	for _, id := range purchased {
+		excluded[id] = true
+	}
+
+	var ids []int64
+	scores := make(map[int64]int)
+	if len(purchased) > 0 {
+		var rows []struct {
+			RelatedID int64
+			Score     int
+		}
+		if err := s.db.Model(&model.CoPurchase{}).
+			Select("related_id, SUM(order_count) AS score").
+			Where("product_id IN ? AND related_id NOT IN ?", purchased, purchased).
+			Group("related_id").Order("score desc, related_id asc").
+			Limit(limit * 3).Scan(&rows).Error; err != nil {
+			return nil, status.Error(codes.Internal, "failed to query recommendations")
+		}
+		for _, r := range rows {
+			ids = append(ids, r.RelatedID)
+			scores[r.RelatedID] = r.Score
+		}
+	}
+	products, err := s.availableProducts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetRecommendationsForUserResponse{}
+	for _, p := range products {
+		if len(resp.Products) == limit {
+			return resp, nil
+		}
+		excluded[p.Id] = true
+		resp.Products = append(resp.Products, convertRecommendedProduct(p, scores[p.Id]))
+	}
+
+	// 购买记录不足时补充热销商品
+	bestSelling, err := s.productClient.ListProducts(ctx, &pbProduct.ListProductsRequest{
+		Page:     1,
+		PageSize: int32(limit * 2),
+		SortBy:   pbProduct.ProductSortBy_PRODUCT_SORT_BEST_SELLING,
+	})
+	if err != nil {
+		log.Printf("Failed to list best selling products: %v", err)
+		return resp, nil
+	}
+	for _, p := range bestSelling.Products {
+		if len(resp.Products) == limit {
+			break
+		}
+		if excluded[p.Id] || !productInStock(p) {
+			continue
+		}
+		excluded[p.Id] = true
+		resp.Products = append(resp.Products, convertRecommendedProduct(p, 0))
+	}
+	return resp, nil
+}
+
+// availableProducts 按 ids 的顺序返回上架且有库存的商品。查询时不透传调用方身份，
+// 未上架的商品对商品服务来说视为不存在
+func (s *OrderService) availableProducts(ctx context.Context, ids []int64) ([]*pbProduct.Product, error) {
+	var products []*pbProduct.Product
+	for start := 0; start < len(ids); start += 100 {
+		end := min(start+100, len(ids))
+		resp, err := s.productClient.BatchGetProducts(ctx, &pbProduct.BatchGetProductsRequest{ProductIds: ids[start:end]})
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to get product info: %v", err)
+		}
+		for _, p := range resp.Products {
+			if productInStock(p) {
+				products = append(products, p)
+			}
+		}
+	}
+	return products, nil
+}
+
+// productInStock 商品或其任一规格有库存
+func productInStock(p *pbProduct.Product) bool {
+	if p.Stock > 0 {
+		return true
+	}
+	for _, sku := range p.Skus {
+		if sku.Stock > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func recommendationLimit(limit int32) int {
+	if limit <= 0 {
+		return defaultRecommendationLimit
+	}
+	if limit > maxRecommendationLimit {
+		return maxRecommendationLimit
+	}
+	return int(limit)
+}
+
+func convertRecommendedProduct(p *pbProduct.Product, score int) *pb.RecommendedProduct {
+	return &pb.RecommendedProduct{
+		ProductId: p.Id,
+		Name:      p.Name,
+		Price:     p.Price,
+		MainImage: p.MainImage,
+		Score:     int32(score),
+	}
+}
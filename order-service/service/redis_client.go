@@ -9,6 +9,9 @@ import (
 
 var RedisClient *redis.Client
 
+// Ctx 用于不随请求取消的 Redis 操作（如释放锁）
+var Ctx = context.Background()
+
 func InitRedis(addr string) {
 	RedisClient = redis.NewClient(&redis.Options{
 		Addr:     addr,
@@ -2,13 +2,18 @@ package main
 
 import (
 	"common/middleware"
+	"context"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"product-service/model"
+	"product-service/search"
 	"product-service/service"
+	"strings"
+	"time"
 
+	pbOrder "common/proto/gen/order"
 	pb "common/proto/gen/product"
 
 	"github.com/joho/godotenv"
@@ -35,9 +40,20 @@ func main() {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	if err := db.AutoMigrate(&model.Product{}); err != nil {
+	// 商品状态字段上线前的商品都已对外销售，迁移后设为上架
+	hasProductStatus := db.Migrator().HasColumn(&model.Product{}, "Status")
+	if err := db.AutoMigrate(&model.Product{}, &model.Category{}, &model.Sku{}, &model.ProductImage{}, &model.Review{}, &model.ReviewVote{}, &model.StockMovement{}, &model.Warehouse{}, &model.WarehouseStock{}, &model.SalesEvent{}, &model.PriceChange{}, &model.PriceSchedule{}, &model.ProductEvent{}); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
+	if !hasProductStatus {
+		if err := db.Model(&model.Product{}).Where("1 = 1").Update("status", int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE)).Error; err != nil {
+			log.Fatalf("failed to migrate product status: %v", err)
+		}
+	}
+	// 首次启用多仓库时将现有库存迁移到默认仓库
+	if err := service.MigrateWarehouseStock(db); err != nil {
+		log.Fatalf("failed to migrate warehouse stock: %v", err)
+	}
 
 	// 初始化 Redis
 	redisAddr := fmt.Sprintf("%s:%s",
@@ -45,6 +61,22 @@ func main() {
 		os.Getenv("REDIS_PORT"),
 	)
 	service.InitRedis(redisAddr)
+	middleware.SetTokenDenylist(service.RedisClient)
+	// 商品列表各查询分类的缓存时间，如 home=2m,category=1m,keyword=30s
+	if err := service.ConfigureListCache(os.Getenv("PRODUCT_LIST_CACHE_TTL")); err != nil {
+		log.Fatalf("failed to configure list cache: %v", err)
+	}
+
+	// 打开商品搜索索引
+	indexPath := os.Getenv("SEARCH_INDEX_PATH")
+	if indexPath == "" {
+		indexPath = "data/product.bleve"
+	}
+	index, created, err := search.Open(indexPath)
+	if err != nil {
+		log.Fatalf("failed to open search index: %v", err)
+	}
+	defer index.Close()
 
 	// 创建 gRPC 服务器
 	port := os.Getenv("GRPC_PORT")
@@ -53,12 +85,68 @@ func main() {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
+	// 连接订单服务，用于校验评价对应的订单
+	orderAddr := os.Getenv("ORDER_SERVICE_ADDR")
+	orderConn, err := grpc.Dial(orderAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("failed to connect to order service: %v", err)
+	}
+	defer orderConn.Close()
+	orderClient := pbOrder.NewOrderServiceClient(orderConn)
+
 	s := grpc.NewServer(
 		grpc.UnaryInterceptor(middleware.JWTMiddleware),
+		grpc.StreamInterceptor(middleware.JWTStreamMiddleware),
 	)
-	productService := service.NewProductService(db)
+	productService := service.NewProductService(db, index, orderClient)
 	pb.RegisterProductServiceServer(s, productService)
 
+	// 消费订单事件，维护商品销量和取消订单的库存释放
+	kafkaBrokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
+	productService.StartOrderEventConsumer(kafkaBrokers, os.Getenv("KAFKA_TOPIC"))
+
+	// 商品变更事件经发件箱发送到 Kafka，检查间隔如 1s
+	productEventTopic := os.Getenv("PRODUCT_EVENT_TOPIC")
+	if productEventTopic == "" {
+		productEventTopic = "product-events"
+	}
+	productEventInterval := service.DefaultProductEventInterval
+	if v := os.Getenv("PRODUCT_EVENT_INTERVAL"); v != "" {
+		if productEventInterval, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("invalid PRODUCT_EVENT_INTERVAL: %v", err)
+		}
+	}
+	productService.StartProductEventPublisher(kafkaBrokers, productEventTopic, productEventInterval)
+
+	// 定时执行到期的调价，检查间隔如 30s
+	schedulerInterval := service.DefaultPriceSchedulerInterval
+	if v := os.Getenv("PRICE_SCHEDULER_INTERVAL"); v != "" {
+		if schedulerInterval, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("invalid PRICE_SCHEDULER_INTERVAL: %v", err)
+		}
+	}
+	productService.StartPriceScheduler(schedulerInterval)
+
+	// 重建商品ID布隆过滤器
+	go func() {
+		if err := productService.RebuildProductFilter(context.Background()); err != nil {
+			log.Printf("failed to build product bloom filter: %v", err)
+			return
+		}
+		log.Println("Product bloom filter built")
+	}()
+
+	// 新建的索引需要从数据库全量构建
+	if created {
+		go func() {
+			if err := productService.RebuildSearchIndex(); err != nil {
+				log.Printf("failed to build search index: %v", err)
+				return
+			}
+			log.Println("Search index built")
+		}()
+	}
+
 	log.Printf("Product service listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
@@ -0,0 +1,12 @@
+package model
+
+import "gorm.io/gorm"
+
+// Category 商品分类，ParentID 为 0 表示顶级分类
+type Category struct {
+	gorm.Model
+	Name      string `gorm:"size:64;not null"`
+	Slug      string `gorm:"size:64;uniqueIndex;not null"`
+	ParentID  uint   `gorm:"not null;default:0;index"`
+	SortOrder int    `gorm:"not null;default:0"`
+}
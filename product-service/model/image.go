@@ -0,0 +1,14 @@
+package model
+
+import "gorm.io/gorm"
+
+// ProductImage 商品图集中的图片，按 SortOrder 排序
+type ProductImage struct {
+	gorm.Model
+	ProductID    uint   `gorm:"not null;index"`
+	URL          string `gorm:"size:256;not null"`
+	ThumbnailURL string `gorm:"size:256"`
+	StorageKey   string `gorm:"size:256"` // 存储中的路径，删除图片时使用
+	ThumbnailKey string `gorm:"size:256"`
+	SortOrder    int    `gorm:"not null;default:0"`
+}
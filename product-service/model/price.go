@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PriceChange 价格变动记录，只追加不修改，每次商品或规格价格变化对应一条记录
+type PriceChange struct {
+	ID         uint    `gorm:"primarykey"`
+	ProductID  uint    `gorm:"not null;index:idx_price_change_product"`
+	SkuID      uint    `gorm:"not null;default:0;index:idx_price_change_product"` // 0 表示商品本身的价格
+	OldPrice   float64 `gorm:"not null;default:0"`
+	NewPrice   float64 `gorm:"not null"`
+	Reason     int     `gorm:"not null"` // 对应 pb.PriceChangeReason
+	ScheduleID uint    `gorm:"not null;default:0"`
+	OperatorID int64   `gorm:"not null;default:0"`
+	CreatedAt  time.Time
+}
+
+// PriceSchedule 定时调价，由商品服务的定时任务按 StartAt 和 EndAt 执行
+type PriceSchedule struct {
+	gorm.Model
+	ProductID     uint       `gorm:"not null;index"`
+	SkuID         uint       `gorm:"not null;default:0"`
+	Price         float64    `gorm:"not null"`
+	StartAt       time.Time  `gorm:"not null;index"`
+	EndAt         *time.Time `gorm:"index"`
+	Status        int        `gorm:"not null;default:0;index"` // 对应 pb.PriceScheduleStatus
+	OriginalPrice float64    `gorm:"not null;default:0"`       // 生效前的价格，到期时恢复
+	Note          string     `gorm:"size:256"`
+	OperatorID    int64      `gorm:"not null;default:0"`
+}
@@ -4,9 +4,19 @@ import "gorm.io/gorm"
 
 type Product struct {
 	gorm.Model
-	Name        string  `gorm:"size:128;not null"`
-	Description string  `gorm:"type:text"`
-	Price       float64 `gorm:"not null"`
-	Stock       int     `gorm:"not null"`
-	MainImage   string  `gorm:"size:256"`
+	Name              string         `gorm:"size:128;not null"`
+	ExternalCode      *string        `gorm:"size:64;uniqueIndex"` // 外部编码，可为空，批量导入时用于匹配商品
+	Description       string         `gorm:"type:text"`
+	Price             float64        `gorm:"not null;index"`
+	Stock             int            `gorm:"not null"`
+	MainImage         string         `gorm:"size:256"`
+	Status            int            `gorm:"not null;default:0;index"` // 对应 pb.ProductStatus
+	SalesCount        int            `gorm:"not null;default:0;index"` // 累计销量，由订单事件更新
+	RatingAvg         float64        `gorm:"not null;default:0;index"` // 平均评分
+	RatingCount       int            `gorm:"not null;default:0"`       // 评分人数
+	LowStockThreshold int            `gorm:"not null;default:0"`       // 低库存预警阈值，0 表示只预警售罄
+	EventSeq          uint64         `gorm:"not null;default:0"`       // 最近一条商品事件的序号
+	Categories        []Category     `gorm:"many2many:product_categories;"`
+	Skus              []Sku          `gorm:"foreignKey:ProductID"`
+	Images            []ProductImage `gorm:"foreignKey:ProductID"`
 }
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ProductEvent 商品变更事件发件箱，与变更在同一事务中写入，由后台任务按商品和序号顺序发送到 Kafka
+type ProductEvent struct {
+	ID          uint   `gorm:"primarykey"`
+	Event       string `gorm:"size:32;not null"`
+	ProductID   uint   `gorm:"not null;index:idx_product_event_seq"`
+	Seq         uint64 `gorm:"not null;default:0;index:idx_product_event_seq"` // 商品内的事件序号，持有商品行锁时分配
+	Before      string `gorm:"type:text"`                                      // 变更前的 JSON，新增时为空
+	After       string `gorm:"type:text"`                                      // 变更后的 JSON，删除时为空
+	Movement    string `gorm:"type:text"`                                      // 库存变化事件对应的库存流水 JSON
+	OperatorID  int64  `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+	PublishedAt *time.Time `gorm:"index"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "gorm.io/gorm"
+
+// Review 商品评价，每个订单项只能评价一次
+type Review struct {
+	gorm.Model
+	ProductID      uint   `gorm:"not null;index:idx_review_product_status"`
+	UserID         int64  `gorm:"not null;index"`
+	Username       string `gorm:"size:64"`
+	OrderID        int64  `gorm:"not null"`
+	OrderItemID    int64  `gorm:"not null;uniqueIndex"`
+	SkuSpec        string `gorm:"size:128"`
+	Rating         int    `gorm:"not null"`
+	Content        string `gorm:"type:text"`
+	Status         int    `gorm:"not null;default:0;index:idx_review_product_status"` // 对应 pb.ReviewStatus
+	HelpfulCount   int    `gorm:"not null;default:0"`
+	ModerationNote string `gorm:"size:256"`
+}
+
+// ReviewVote 评价的“有帮助”投票，每个用户对每条评价只能投一次
+type ReviewVote struct {
+	ID       uint  `gorm:"primarykey"`
+	ReviewID uint  `gorm:"not null;uniqueIndex:idx_review_vote"`
+	UserID   int64 `gorm:"not null;uniqueIndex:idx_review_vote"`
+}
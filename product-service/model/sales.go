@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// SalesEvent 已处理的订单销量事件，用于保证重复消费时销量只统计一次
+type SalesEvent struct {
+	ID        uint   `gorm:"primarykey"`
+	OrderID   int64  `gorm:"not null;uniqueIndex:idx_sales_event"`
+	Event     string `gorm:"size:32;not null;uniqueIndex:idx_sales_event"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Sku 商品规格，拥有独立的价格、库存和图片
+type Sku struct {
+	gorm.Model
+	ProductID  uint    `gorm:"not null;index"`
+	SkuCode    string  `gorm:"size:64;uniqueIndex;not null"`
+	Attributes string  `gorm:"type:text"` // 规格属性，JSON 格式，如 {"颜色":"红","尺码":"M"}
+	Price      float64 `gorm:"not null"`
+	Stock      int     `gorm:"not null"`
+	Image      string  `gorm:"size:256"`
+}
+
+// AttributeMap 解析规格属性
+func (s *Sku) AttributeMap() map[string]string {
+	attrs := make(map[string]string)
+	if s.Attributes != "" {
+		_ = json.Unmarshal([]byte(s.Attributes), &attrs)
+	}
+	return attrs
+}
+
+// SetAttributes 设置规格属性
+func (s *Sku) SetAttributes(attrs map[string]string) {
+	if len(attrs) == 0 {
+		s.Attributes = ""
+		return
+	}
+	bytes, _ := json.Marshal(attrs)
+	s.Attributes = string(bytes)
+}
+
+// Spec 规格描述，按属性名排序，如 "尺码:M 颜色:红"
+func (s *Sku) Spec() string {
+	attrs := s.AttributeMap()
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+":"+attrs[k])
+	}
+	return strings.Join(parts, " ")
+}
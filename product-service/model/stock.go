@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// StockMovement 库存流水，只追加不修改，每次库存变化对应一条记录
+type StockMovement struct {
+	ID          uint   `gorm:"primarykey"`
+	ProductID   uint   `gorm:"not null;index:idx_stock_movement_product"`
+	SkuID       uint   `gorm:"not null;default:0;index:idx_stock_movement_product"` // 0 表示商品本身的库存
+	WarehouseID uint   `gorm:"not null;default:0;index"`
+	Delta       int    `gorm:"not null"`
+	StockAfter  int    `gorm:"not null"` // 该仓库变化后的库存
+	Reason      int    `gorm:"not null"` // 对应 pb.StockReason
+	Reference   string `gorm:"size:64;index"`
+	Note        string `gorm:"size:256"`
+	OperatorID  int64  `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+}
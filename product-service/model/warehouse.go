@@ -0,0 +1,24 @@
+package model
+
+import "gorm.io/gorm"
+
+// Warehouse 仓库，Priority 越小越优先
+type Warehouse struct {
+	gorm.Model
+	Code      string  `gorm:"size:32;uniqueIndex;not null"`
+	Name      string  `gorm:"size:64;not null"`
+	Latitude  float64 `gorm:"not null;default:0"`
+	Longitude float64 `gorm:"not null;default:0"`
+	Priority  int     `gorm:"not null;default:0"`
+	Active    bool    `gorm:"not null;default:true"`
+}
+
+// WarehouseStock 商品或规格在某个仓库的库存，无规格商品的 SkuID 为 0。
+// Product.Stock 和 Sku.Stock 为各仓库库存之和
+type WarehouseStock struct {
+	ID          uint `gorm:"primarykey"`
+	WarehouseID uint `gorm:"not null;uniqueIndex:idx_warehouse_stock"`
+	ProductID   uint `gorm:"not null;uniqueIndex:idx_warehouse_stock;index"`
+	SkuID       uint `gorm:"not null;default:0;uniqueIndex:idx_warehouse_stock"`
+	Stock       int  `gorm:"not null;default:0"`
+}
@@ -0,0 +1,239 @@
+// Package search 基于 Bleve 的商品全文索引，支持中文分词、相关度排序和分面统计
+package search
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	priceFacetName    = "price"
+	categoryFacetName = "category"
+	maxCategoryFacets = 20
+)
+
+// priceRanges 价格分面的区间，Max 为 0 表示不设上限
+var priceRanges = []PriceRange{
+	{Min: 0, Max: 50},
+	{Min: 50, Max: 100},
+	{Min: 100, Max: 200},
+	{Min: 200, Max: 500},
+	{Min: 500, Max: 1000},
+	{Min: 1000, Max: 0},
+}
+
+// Document 索引中的商品文档
+type Document struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Price       float64  `json:"price"`
+	CategoryIDs []string `json:"category_ids"`
+}
+
+// NewDocument 构造商品文档
+func NewDocument(name, description string, price float64, categoryIDs []int64) Document {
+	doc := Document{Name: name, Description: description, Price: price}
+	for _, id := range categoryIDs {
+		doc.CategoryIDs = append(doc.CategoryIDs, strconv.FormatInt(id, 10))
+	}
+	return doc
+}
+
+// Query 搜索条件
+type Query struct {
+	Keyword     string
+	MinPrice    float64
+	MaxPrice    float64
+	CategoryIDs []int64 // 命中任一分类即可
+	From        int
+	Size        int
+}
+
+type Hit struct {
+	ID    int64
+	Score float64
+}
+
+type PriceRange struct {
+	Min   float64
+	Max   float64
+	Count int
+}
+
+type CategoryCount struct {
+	CategoryID int64
+	Count      int
+}
+
+// Result 搜索结果
+type Result struct {
+	Hits           []Hit
+	Total          uint64
+	PriceFacets    []PriceRange
+	CategoryFacets []CategoryCount
+}
+
+// Index 商品索引
+type Index struct {
+	index bleve.Index
+}
+
+// Open 打开索引，不存在时新建；created 为 true 表示需要全量构建
+func Open(path string) (idx *Index, created bool, err error) {
+	if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+		index, err := bleve.New(path, newMapping())
+		if err != nil {
+			return nil, false, err
+		}
+		return &Index{index: index}, true, nil
+	}
+	index, err := bleve.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return &Index{index: index}, false, nil
+}
+
+func newMapping() mapping.IndexMapping {
+	// 商品名和描述使用 CJK 分词，兼顾中文和英文
+	textField := bleve.NewTextFieldMapping()
+	textField.Analyzer = cjk.AnalyzerName
+	priceField := bleve.NewNumericFieldMapping()
+	categoryField := bleve.NewTextFieldMapping()
+	categoryField.Analyzer = keyword.Name
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("name", textField)
+	doc.AddFieldMappingsAt("description", textField)
+	doc.AddFieldMappingsAt("price", priceField)
+	doc.AddFieldMappingsAt("category_ids", categoryField)
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = doc
+	m.DefaultAnalyzer = cjk.AnalyzerName
+	return m
+}
+
+func docID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// Put 新增或更新商品文档
+func (i *Index) Put(id int64, doc Document) error {
+	return i.index.Index(docID(id), doc)
+}
+
+// Delete 删除商品文档
+func (i *Index) Delete(id int64) error {
+	return i.index.Delete(docID(id))
+}
+
+// PutBatch 批量写入商品文档
+func (i *Index) PutBatch(docs map[int64]Document) error {
+	batch := i.index.NewBatch()
+	for id, doc := range docs {
+		if err := batch.Index(docID(id), doc); err != nil {
+			return err
+		}
+	}
+	return i.index.Batch(batch)
+}
+
+// Close 关闭索引
+func (i *Index) Close() error {
+	return i.index.Close()
+}
+
+// Search 按相关度搜索商品，同时统计价格区间和分类分面
+func (i *Index) Search(q Query) (*Result, error) {
+	var conjuncts []query.Query
+	if q.Keyword != "" {
+		name := bleve.NewMatchQuery(q.Keyword)
+		name.SetField("name")
+		name.SetBoost(3)
+		description := bleve.NewMatchQuery(q.Keyword)
+		description.SetField("description")
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(name, description))
+	} else {
+		conjuncts = append(conjuncts, bleve.NewMatchAllQuery())
+	}
+	if q.MinPrice > 0 || q.MaxPrice > 0 {
+		var lo, hi *float64
+		inclusive := true
+		if q.MinPrice > 0 {
+			lo = &q.MinPrice
+		}
+		if q.MaxPrice > 0 {
+			hi = &q.MaxPrice
+		}
+		price := bleve.NewNumericRangeInclusiveQuery(lo, hi, &inclusive, &inclusive)
+		price.SetField("price")
+		conjuncts = append(conjuncts, price)
+	}
+	if len(q.CategoryIDs) > 0 {
+		var terms []query.Query
+		for _, id := range q.CategoryIDs {
+			term := bleve.NewTermQuery(strconv.FormatInt(id, 10))
+			term.SetField("category_ids")
+			terms = append(terms, term)
+		}
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(terms...))
+	}
+
+	req := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(conjuncts...), q.Size, q.From, false)
+	priceFacet := bleve.NewFacetRequest("price", len(priceRanges))
+	for _, r := range priceRanges {
+		lo, hi := r.Min, r.Max
+		if hi > 0 {
+			priceFacet.AddNumericRange(rangeName(r), &lo, &hi)
+		} else {
+			priceFacet.AddNumericRange(rangeName(r), &lo, nil)
+		}
+	}
+	req.AddFacet(priceFacetName, priceFacet)
+	req.AddFacet(categoryFacetName, bleve.NewFacetRequest("category_ids", maxCategoryFacets))
+
+	res, err := i.index.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Total: res.Total}
+	for _, hit := range res.Hits {
+		id, err := strconv.ParseInt(hit.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		result.Hits = append(result.Hits, Hit{ID: id, Score: hit.Score})
+	}
+	if facet, ok := res.Facets[priceFacetName]; ok {
+		counts := make(map[string]int)
+		for _, nr := range facet.NumericRanges {
+			counts[nr.Name] = nr.Count
+		}
+		for _, r := range priceRanges {
+			if count := counts[rangeName(r)]; count > 0 {
+				result.PriceFacets = append(result.PriceFacets, PriceRange{Min: r.Min, Max: r.Max, Count: count})
+			}
+		}
+	}
+	if facet, ok := res.Facets[categoryFacetName]; ok && facet.Terms != nil {
+		for _, term := range facet.Terms.Terms() {
+			id, err := strconv.ParseInt(term.Term, 10, 64)
+			if err != nil {
+				continue
+			}
+			result.CategoryFacets = append(result.CategoryFacets, CategoryCount{CategoryID: id, Count: term.Count})
+		}
+	}
+	return result, nil
+}
+
+func rangeName(r PriceRange) string {
+	return strconv.FormatFloat(r.Min, 'f', -1, 64) + "-" + strconv.FormatFloat(r.Max, 'f', -1, 64)
+}
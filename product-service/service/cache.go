@@ -0,0 +1,97 @@
+package service
+
+import (
+	"common/cache"
+	"context"
+	"fmt"
+	"log"
+	"product-service/model"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	productDetailTTL = 5 * time.Minute
+	notFoundTTL      = time.Minute
+	cacheTTLJitter   = 0.2
+
+	// productFilterKey 商品ID布隆过滤器，用于拦截不存在的商品ID
+	productFilterKey      = "product:bloom:ids"
+	productFilterCapacity = 1000000
+
+	// doubleDeleteDelay 延迟双删的间隔，需大于一次读库并回写缓存的耗时
+	doubleDeleteDelay = 500 * time.Millisecond
+)
+
+var (
+	detailCache   *cache.Cache
+	productFilter *cache.BloomFilter
+)
+
+// productDetailKey 商品详情缓存的 key，缓存内容的结构变化时升级版本，避免读到旧格式的数据
+func productDetailKey(productID int64) string {
+	return fmt.Sprintf("product:detail:v2:%d", productID)
+}
+
+func productDetailKeys(productIDs []int64) []string {
+	keys := make([]string, 0, len(productIDs))
+	for _, id := range productIDs {
+		keys = append(keys, productDetailKey(id))
+	}
+	return keys
+}
+
+// invalidateProduct 商品写入后清理详情缓存以及包含这些商品的列表缓存，
+// tags 为额外需要失效的列表标签。
+// 先删一次缓存，延迟一段时间后再删一次，避免并发读请求在写库前读到旧数据并回写缓存
+func invalidateProduct(ctx context.Context, productIDs []int64, tags ...string) {
+	keys := productDetailKeys(productIDs)
+	for _, id := range productIDs {
+		tags = append(tags, productListTag(id))
+	}
+	invalidate := func(ctx context.Context) {
+		if err := detailCache.Delete(ctx, keys...); err != nil {
+			log.Printf("failed to delete product cache %v: %v", keys, err)
+		}
+		invalidateListTags(ctx, tags...)
+	}
+	invalidate(ctx)
+	time.AfterFunc(doubleDeleteDelay, func() {
+		// 请求上下文此时可能已取消，使用独立的上下文
+		invalidate(context.Background())
+	})
+}
+
+// productMayExist 通过布隆过滤器判断商品是否可能存在，Redis 出错时放行
+func productMayExist(ctx context.Context, productID int64) bool {
+	ok, err := productFilter.MightContain(ctx, strconv.FormatInt(productID, 10))
+	if err != nil {
+		log.Printf("failed to check product bloom filter: %v", err)
+		return true
+	}
+	return ok
+}
+
+// addToProductFilter 新建商品后加入布隆过滤器
+func addToProductFilter(ctx context.Context, productID int64) {
+	if err := productFilter.Add(ctx, strconv.FormatInt(productID, 10)); err != nil {
+		log.Printf("failed to add product %d to bloom filter: %v", productID, err)
+	}
+}
+
+// RebuildProductFilter 从数据库全量重建商品ID布隆过滤器。
+// 布隆过滤器不支持删除，已删除的商品由空值缓存兜底，重建时一并清理
+func (s *ProductService) RebuildProductFilter(ctx context.Context) error {
+	return productFilter.Rebuild(ctx, func(add func(items ...string) error) error {
+		var products []model.Product
+		return s.db.Select("id").FindInBatches(&products, 1000, func(tx *gorm.DB, batch int) error {
+			items := make([]string, 0, len(products))
+			for _, p := range products {
+				items = append(items, strconv.FormatUint(uint64(p.ID), 10))
+			}
+			return add(items...)
+		}).Error
+	})
+}
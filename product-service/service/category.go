@@ -0,0 +1,266 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"fmt"
+	"product-service/model"
+	"regexp"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func (s *ProductService) CreateCategory(ctx context.Context, req *pb.CreateCategoryRequest) (*pb.CreateCategoryResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "category name is required")
+	}
+	if !slugPattern.MatchString(req.Slug) {
+		return nil, status.Error(codes.InvalidArgument, "slug must be lowercase letters, digits and hyphens")
+	}
+	if req.ParentId != 0 {
+		if err := s.db.First(&model.Category{}, req.ParentId).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, status.Error(codes.NotFound, "parent category not found")
+			}
+			return nil, status.Error(codes.Internal, "failed to query parent category")
+		}
+	}
+	// 包含已删除的分类，唯一索引对已删除的记录同样生效
+	var count int64
+	s.db.Unscoped().Model(&model.Category{}).Where("slug = ?", req.Slug).Count(&count)
+	if count > 0 {
+		return nil, status.Error(codes.AlreadyExists, "slug already exists")
+	}
+	category := model.Category{
+		Name:      req.Name,
+		Slug:      req.Slug,
+		ParentID:  uint(req.ParentId),
+		SortOrder: int(req.SortOrder),
+	}
+	if err := s.db.Create(&category).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to create category")
+	}
+	return &pb.CreateCategoryResponse{
+		CategoryId: int64(category.ID),
+		Message:    "category created successfully",
+	}, nil
+}
+
+func (s *ProductService) UpdateCategory(ctx context.Context, req *pb.UpdateCategoryRequest) (*pb.UpdateCategoryResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var category model.Category
+	if err := s.db.First(&category, req.CategoryId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.UpdateCategoryResponse{Success: false, Message: "category not found"}, nil
+		}
+		return &pb.UpdateCategoryResponse{Success: false, Message: "failed to query category"}, nil
+	}
+	if req.Name != "" {
+		category.Name = req.Name
+	}
+	if req.Slug != "" && req.Slug != category.Slug {
+		if !slugPattern.MatchString(req.Slug) {
+			return &pb.UpdateCategoryResponse{Success: false, Message: "slug must be lowercase letters, digits and hyphens"}, nil
+		}
+		var count int64
+		s.db.Unscoped().Model(&model.Category{}).Where("slug = ? AND id <> ?", req.Slug, category.ID).Count(&count)
+		if count > 0 {
+			return nil, status.Error(codes.AlreadyExists, "slug already exists")
+		}
+		category.Slug = req.Slug
+	}
+	if req.SortOrder != 0 {
+		category.SortOrder = int(req.SortOrder)
+	}
+	if req.Move {
+		if req.ParentId != 0 {
+			// 不能移动到自身或自身的子孙分类下
+			descendants, err := s.categoryDescendants(int64(category.ID))
+			if err != nil {
+				return &pb.UpdateCategoryResponse{Success: false, Message: "failed to query categories"}, nil
+			}
+			for _, id := range descendants {
+				if id == req.ParentId {
+					return &pb.UpdateCategoryResponse{Success: false, Message: "cannot move category under itself or its descendants"}, nil
+				}
+			}
+			if err := s.db.First(&model.Category{}, req.ParentId).Error; err != nil {
+				return &pb.UpdateCategoryResponse{Success: false, Message: "parent category not found"}, nil
+			}
+		}
+		category.ParentID = uint(req.ParentId)
+	}
+	if err := s.db.Save(&category).Error; err != nil {
+		return &pb.UpdateCategoryResponse{Success: false, Message: "failed to update category"}, nil
+	}
+	if req.Move {
+		// 分类层级变化会影响按分类筛选的列表
+		invalidateProductLists(ctx)
+	}
+	return &pb.UpdateCategoryResponse{Success: true, Message: "category updated successfully"}, nil
+}
+
+func (s *ProductService) DeleteCategory(ctx context.Context, req *pb.DeleteCategoryRequest) (*pb.DeleteCategoryResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var children int64
+	if err := s.db.Model(&model.Category{}).Where("parent_id = ?", req.CategoryId).Count(&children).Error; err != nil {
+		return &pb.DeleteCategoryResponse{Success: false, Message: "failed to query category"}, nil
+	}
+	if children > 0 {
+		return &pb.DeleteCategoryResponse{Success: false, Message: "category has child categories"}, nil
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 分类下的商品移出该分类，删除前记下快照，之后为每个商品记录修改事件
+		var productIDs []uint
+		if err := tx.Table("product_categories").Where("category_id = ?", req.CategoryId).
+			Order("product_id asc").Pluck("product_id", &productIDs).Error; err != nil {
+			return err
+		}
+		befores := make([]*pb.Product, len(productIDs))
+		for i, id := range productIDs {
+			before, err := productSnapshot(tx, id)
+			if err != nil {
+				return err
+			}
+			befores[i] = before
+		}
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", req.CategoryId).Error; err != nil {
+			return err
+		}
+		// 释放 slug，之后可以重新使用。slug 不能包含冒号，不会与有效的 slug 冲突
+		if err := tx.Model(&model.Category{}).Where("id = ?", req.CategoryId).
+			Update("slug", fmt.Sprintf("deleted:%d", req.CategoryId)).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Category{}, req.CategoryId).Error; err != nil {
+			return err
+		}
+		for i, id := range productIDs {
+			if err := recordProductChange(tx, id, operatorID(ctx), befores[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &pb.DeleteCategoryResponse{Success: false, Message: "failed to delete category"}, nil
+	}
+	// 分类下的商品列表缓存一并失效
+	invalidateProductLists(ctx)
+	return &pb.DeleteCategoryResponse{Success: true, Message: "category deleted successfully"}, nil
+}
+
+// ListCategories 返回分类树
+func (s *ProductService) ListCategories(ctx context.Context, req *pb.ListCategoriesRequest) (*pb.ListCategoriesResponse, error) {
+	var categories []model.Category
+	if err := s.db.Order("sort_order asc, id asc").Find(&categories).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to list categories")
+	}
+	nodes := make(map[int64]*pb.Category, len(categories))
+	for _, c := range categories {
+		nodes[int64(c.ID)] = &pb.Category{
+			Id:        int64(c.ID),
+			Name:      c.Name,
+			Slug:      c.Slug,
+			ParentId:  int64(c.ParentID),
+			SortOrder: int32(c.SortOrder),
+		}
+	}
+	// categories 已按 sort_order 排序，按顺序挂到父节点下即可保持顺序
+	var roots []*pb.Category
+	for _, c := range categories {
+		node := nodes[int64(c.ID)]
+		if parent, ok := nodes[node.ParentId]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	if req.ParentId != 0 {
+		parent, ok := nodes[req.ParentId]
+		if !ok {
+			return nil, status.Error(codes.NotFound, "category not found")
+		}
+		roots = parent.Children
+	}
+	return &pb.ListCategoriesResponse{Categories: roots}, nil
+}
+
+func (s *ProductService) SetProductCategories(ctx context.Context, req *pb.SetProductCategoriesRequest) (*pb.SetProductCategoriesResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var product model.Product
+	if err := s.db.First(&product, req.ProductId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.SetProductCategoriesResponse{Success: false, Message: "product not found"}, nil
+		}
+		return &pb.SetProductCategoriesResponse{Success: false, Message: "failed to query product"}, nil
+	}
+	categories, err := s.loadCategories(req.CategoryIds)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, product.ID, operatorID(ctx), func() error {
+			return tx.Model(&product).Association("Categories").Replace(categories)
+		})
+	})
+	if err != nil {
+		return &pb.SetProductCategoriesResponse{Success: false, Message: "failed to set product categories"}, nil
+	}
+	s.indexProduct(req.ProductId)
+	invalidateProduct(ctx, []int64{req.ProductId})
+	invalidateProductLists(ctx)
+	return &pb.SetProductCategoriesResponse{Success: true, Message: "product categories updated"}, nil
+}
+
+// loadCategories 按ID加载分类，存在不合法的ID时返回错误
+func (s *ProductService) loadCategories(ids []int64) ([]model.Category, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var categories []model.Category
+	if err := s.db.Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query categories")
+	}
+	found := make(map[int64]bool, len(categories))
+	for _, c := range categories {
+		found[int64(c.ID)] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, status.Errorf(codes.InvalidArgument, "category %d not found", id)
+		}
+	}
+	return categories, nil
+}
+
+// categoryDescendants 返回分类自身及其所有子孙分类的ID
+func (s *ProductService) categoryDescendants(categoryID int64) ([]int64, error) {
+	var categories []model.Category
+	if err := s.db.Select("id", "parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[int64][]int64)
+	for _, c := range categories {
+		children[int64(c.ParentID)] = append(children[int64(c.ParentID)], int64(c.ID))
+	}
+	ids := []int64{categoryID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"product-service/model"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// maxProductImages 每个商品图集的图片数量上限
+const maxProductImages = 20
+
+func (s *ProductService) AddProductImage(ctx context.Context, req *pb.AddProductImageRequest) (*pb.AddProductImageResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Url == "" {
+		return nil, status.Error(codes.InvalidArgument, "image url is required")
+	}
+	var product model.Product
+	if err := s.db.First(&product, req.ProductId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "product not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to query product")
+	}
+	image := model.ProductImage{
+		ProductID:    product.ID,
+		URL:          req.Url,
+		ThumbnailURL: req.ThumbnailUrl,
+		StorageKey:   req.StorageKey,
+		ThumbnailKey: req.ThumbnailKey,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		before, err := productSnapshot(tx, product.ID)
+		if err != nil {
+			return err
+		}
+		var count int64
+		var maxOrder int
+		if err := tx.Model(&model.ProductImage{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxProductImages {
+			return status.Errorf(codes.FailedPrecondition, "a product can have at most %d images", maxProductImages)
+		}
+		if err := tx.Model(&model.ProductImage{}).Where("product_id = ?", product.ID).
+			Select("COALESCE(MAX(sort_order), -1)").Scan(&maxOrder).Error; err != nil {
+			return err
+		}
+		// 新图片追加到图集末尾
+		image.SortOrder = maxOrder + 1
+		if err := tx.Create(&image).Error; err != nil {
+			return err
+		}
+		// 商品没有主图时使用图集第一张
+		if product.MainImage == "" {
+			if err := tx.Model(&product).Update("main_image", image.URL).Error; err != nil {
+				return err
+			}
+		}
+		return recordProductChange(tx, product.ID, operatorID(ctx), before)
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Internal, "failed to add product image")
+	}
+	invalidateProduct(ctx, []int64{int64(product.ID)})
+	return &pb.AddProductImageResponse{
+		Image:   convertProductImageToPB(&image),
+		Message: "product image added successfully",
+	}, nil
+}
+
+func (s *ProductService) DeleteProductImage(ctx context.Context, req *pb.DeleteProductImageRequest) (*pb.DeleteProductImageResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var image model.ProductImage
+	if err := s.db.First(&image, req.ImageId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.DeleteProductImageResponse{Success: false, Message: "image not found"}, nil
+		}
+		return &pb.DeleteProductImageResponse{Success: false, Message: "failed to query image"}, nil
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, image.ProductID, operatorID(ctx), func() error {
+			// 图片文件由调用方清理，记录直接物理删除
+			if err := tx.Unscoped().Delete(&image).Error; err != nil {
+				return err
+			}
+			// 删除的是主图时改用图集中的下一张
+			var product model.Product
+			if err := tx.First(&product, image.ProductID).Error; err == nil && product.MainImage == image.URL {
+				var next model.ProductImage
+				mainImage := ""
+				if err := tx.Where("product_id = ?", image.ProductID).Order("sort_order asc, id asc").First(&next).Error; err == nil {
+					mainImage = next.URL
+				}
+				return tx.Model(&product).Update("main_image", mainImage).Error
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return &pb.DeleteProductImageResponse{Success: false, Message: "failed to delete image"}, nil
+	}
+	invalidateProduct(ctx, []int64{int64(image.ProductID)})
+
+	var keys []string
+	for _, key := range []string{image.StorageKey, image.ThumbnailKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return &pb.DeleteProductImageResponse{Success: true, Message: "image deleted successfully", StorageKeys: keys}, nil
+}
+
+func (s *ProductService) ReorderProductImages(ctx context.Context, req *pb.ReorderProductImagesRequest) (*pb.ReorderProductImagesResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var images []model.ProductImage
+	if err := s.db.Where("product_id = ?", req.ProductId).Find(&images).Error; err != nil {
+		return &pb.ReorderProductImagesResponse{Success: false, Message: "failed to query images"}, nil
+	}
+	// 必须给出该商品的全部图片且不重复
+	current := make(map[int64]bool, len(images))
+	for _, img := range images {
+		current[int64(img.ID)] = true
+	}
+	if len(req.ImageIds) != len(images) {
+		return &pb.ReorderProductImagesResponse{Success: false, Message: "image_ids must contain all images of the product"}, nil
+	}
+	for _, id := range req.ImageIds {
+		if !current[id] {
+			return &pb.ReorderProductImagesResponse{Success: false, Message: "image_ids must contain all images of the product"}, nil
+		}
+		delete(current, id)
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, uint(req.ProductId), operatorID(ctx), func() error {
+			for i, id := range req.ImageIds {
+				if err := tx.Model(&model.ProductImage{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return &pb.ReorderProductImagesResponse{Success: false, Message: "failed to reorder images"}, nil
+	}
+	invalidateProduct(ctx, []int64{req.ProductId})
+	return &pb.ReorderProductImagesResponse{Success: true, Message: "images reordered successfully"}, nil
+}
+
+// preloadProductDetails 预加载商品的分类、规格和按顺序排列的图集
+func preloadProductDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("Categories").Preload("Skus").Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order asc, id asc")
+	})
+}
+
+func convertProductImageToPB(image *model.ProductImage) *pb.ProductImage {
+	return &pb.ProductImage{
+		Id:           int64(image.ID),
+		ProductId:    int64(image.ProductID),
+		Url:          image.URL,
+		ThumbnailUrl: image.ThumbnailURL,
+		SortOrder:    int32(image.SortOrder),
+	}
+}
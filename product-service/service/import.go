@@ -0,0 +1,368 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"product-service/model"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImportRows 单次导入的行数上限
+	maxImportRows = 10000
+
+	// productExportBatchSize 导出时每批从数据库读取的商品数量
+	productExportBatchSize = 500
+)
+
+// productStatusNames 导入导出时商品状态的写法
+var productStatusNames = map[pb.ProductStatus]string{
+	pb.ProductStatus_PRODUCT_STATUS_DRAFT:    "draft",
+	pb.ProductStatus_PRODUCT_STATUS_ACTIVE:   "active",
+	pb.ProductStatus_PRODUCT_STATUS_ARCHIVED: "archived",
+}
+
+// importRow 校验后的导入行，指针字段为 nil 表示单元格为空，更新时保持原值
+type importRow struct {
+	line        int32
+	code        string
+	name        *string
+	description *string
+	price       *float64
+	stock       *int
+	mainImage   *string
+	categoryIDs []int64
+	status      *pb.ProductStatus
+}
+
+// ImportProducts 批量导入商品，按外部编码新增或更新。每行单独写入，出错的行记录到结果中，不影响其他行
+func (s *ProductService) ImportProducts(stream pb.ProductService_ImportProductsServer) error {
+	ctx := stream.Context()
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return err
+	}
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "import options are required")
+	}
+	if err != nil {
+		return err
+	}
+	opts := first.GetOptions()
+	if opts == nil {
+		return status.Error(codes.InvalidArgument, "the first message must be import options")
+	}
+
+	resp := &pb.ImportProductsResponse{DryRun: opts.DryRun}
+	seen := make(map[string]int32)
+	var changed []int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		row := req.GetRow()
+		if row == nil {
+			return status.Error(codes.InvalidArgument, "import options must only be sent once")
+		}
+		if resp.Total++; resp.Total > maxImportRows {
+			return status.Errorf(codes.InvalidArgument, "at most %d rows per import", maxImportRows)
+		}
+		productID, created, err := s.importProduct(ctx, row, seen, opts.DryRun)
+		if err != nil {
+			resp.Failed++
+			resp.Errors = append(resp.Errors, &pb.ImportRowError{
+				Line:         row.Line,
+				ExternalCode: row.ExternalCode,
+				Message:      err.Error(),
+			})
+			continue
+		}
+		if created {
+			resp.Created++
+		} else {
+			resp.Updated++
+		}
+		if productID > 0 {
+			changed = append(changed, productID)
+		}
+	}
+
+	if len(changed) > 0 {
+		for _, id := range changed {
+			s.indexProduct(id)
+			addToProductFilter(ctx, id)
+		}
+		invalidateProduct(ctx, changed)
+		invalidateProductLists(ctx)
+	}
+	return stream.SendAndClose(resp)
+}
+
+// importProduct 校验并写入一行，返回商品ID和是否为新增；试运行时不写入，商品ID为 0
+func (s *ProductService) importProduct(ctx context.Context, row *pb.ProductRow, seen map[string]int32, dryRun bool) (int64, bool, error) {
+	in, err := parseProductRow(row)
+	if err != nil {
+		return 0, false, err
+	}
+	if line, ok := seen[in.code]; ok {
+		return 0, false, fmt.Errorf("duplicate external_code, first seen on line %d", line)
+	}
+	seen[in.code] = in.line
+
+	var categories []model.Category
+	if in.categoryIDs != nil {
+		if categories, err = s.loadCategories(in.categoryIDs); err != nil {
+			return 0, false, fmt.Errorf("category_ids: %s", status.Convert(err).Message())
+		}
+	}
+
+	var product model.Product
+	err = s.db.Preload("Skus").Where("external_code = ?", in.code).First(&product).Error
+	if err == gorm.ErrRecordNotFound {
+		return s.importNewProduct(ctx, in, categories, dryRun)
+	}
+	if err != nil {
+		return 0, false, errors.New("failed to query product")
+	}
+
+	if in.stock != nil && len(product.Skus) > 0 {
+		return 0, false, errors.New("stock: product has skus, adjust stock per sku instead")
+	}
+	if dryRun {
+		return 0, false, nil
+	}
+	if in.name != nil {
+		product.Name = *in.name
+	}
+	if in.description != nil {
+		product.Description = *in.description
+	}
+	if in.mainImage != nil {
+		product.MainImage = *in.mainImage
+	}
+	if in.status != nil {
+		product.Status = int(*in.status)
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, product.ID, operatorID(ctx), func() error {
+			if err := tx.Omit("Stock", "Price", "EventSeq", "Skus").Save(&product).Error; err != nil {
+				return err
+			}
+			if in.price != nil {
+				if err := changePrice(tx, &model.PriceChange{
+					ProductID:  product.ID,
+					NewPrice:   *in.price,
+					Reason:     int(pb.PriceChangeReason_PRICE_REASON_IMPORT),
+					OperatorID: operatorID(ctx),
+				}); err != nil {
+					return err
+				}
+			}
+			if in.categoryIDs != nil {
+				if err := tx.Model(&product).Association("Categories").Replace(categories); err != nil {
+					return err
+				}
+			}
+			if in.stock != nil {
+				return setProductStock(tx, product.ID, *in.stock, fmt.Sprintf("import line %d", in.line), operatorID(ctx))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			return 0, false, errors.New(st.Message())
+		}
+		return 0, false, errors.New("failed to update product")
+	}
+	return int64(product.ID), false, nil
+}
+
+func (s *ProductService) importNewProduct(ctx context.Context, in *importRow, categories []model.Category, dryRun bool) (int64, bool, error) {
+	if in.name == nil {
+		return 0, false, errors.New("name: required for new products")
+	}
+	if in.price == nil {
+		return 0, false, errors.New("price: required for new products")
+	}
+	if dryRun {
+		return 0, true, nil
+	}
+	code := in.code
+	product := model.Product{
+		Name:         *in.name,
+		ExternalCode: &code,
+		Price:        *in.price,
+		Categories:   categories,
+	}
+	if in.description != nil {
+		product.Description = *in.description
+	}
+	if in.mainImage != nil {
+		product.MainImage = *in.mainImage
+	}
+	if in.stock != nil {
+		product.Stock = *in.stock
+	}
+	if in.status != nil {
+		product.Status = int(*in.status)
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if err := initPrice(tx, product.ID, 0, product.Price, operatorID(ctx)); err != nil {
+			return err
+		}
+		if err := initStock(tx, product.ID, 0, product.Stock, operatorID(ctx)); err != nil {
+			return err
+		}
+		return recordProductChange(tx, product.ID, operatorID(ctx), nil)
+	})
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			return 0, false, errors.New(st.Message())
+		}
+		return 0, false, errors.New("failed to create product")
+	}
+	return int64(product.ID), true, nil
+}
+
+// parseProductRow 校验一行数据，错误信息以列名开头
+func parseProductRow(row *pb.ProductRow) (*importRow, error) {
+	in := &importRow{line: row.Line, code: strings.TrimSpace(row.ExternalCode)}
+	if in.code == "" {
+		return nil, errors.New("external_code: required")
+	}
+	if len(in.code) > 64 {
+		return nil, errors.New("external_code: must be at most 64 characters")
+	}
+	if v := strings.TrimSpace(row.Name); v != "" {
+		if len([]rune(v)) > 128 {
+			return nil, errors.New("name: must be at most 128 characters")
+		}
+		in.name = &v
+	}
+	if v := strings.TrimSpace(row.Description); v != "" {
+		in.description = &v
+	}
+	if v := strings.TrimSpace(row.Price); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price <= 0 {
+			return nil, errors.New("price: must be a positive number")
+		}
+		in.price = &price
+	}
+	if v := strings.TrimSpace(row.Stock); v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil || stock < 0 {
+			return nil, errors.New("stock: must be a non-negative integer")
+		}
+		in.stock = &stock
+	}
+	if v := strings.TrimSpace(row.MainImage); v != "" {
+		if len(v) > 256 {
+			return nil, errors.New("main_image: must be at most 256 characters")
+		}
+		in.mainImage = &v
+	}
+	if v := strings.TrimSpace(row.CategoryIds); v != "" {
+		in.categoryIDs = []int64{}
+		for _, part := range strings.Split(v, ";") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(part, 10, 64)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("category_ids: invalid category id %q", part)
+			}
+			in.categoryIDs = append(in.categoryIDs, id)
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(row.Status)); v != "" {
+		for st, name := range productStatusNames {
+			if name == v {
+				in.status = &st
+				break
+			}
+		}
+		if in.status == nil {
+			return nil, fmt.Errorf("status: must be draft, active or archived, got %q", v)
+		}
+	}
+	return in, nil
+}
+
+// ExportProducts 按条件流式导出商品，分批读取避免一次性加载到内存
+func (s *ProductService) ExportProducts(req *pb.ExportProductsRequest, stream pb.ProductService_ExportProductsServer) error {
+	ctx := stream.Context()
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return err
+	}
+	q := s.db.WithContext(ctx).Model(&model.Product{})
+	if kw := strings.TrimSpace(req.Keyword); kw != "" {
+		q = q.Where("name LIKE ?", "%"+kw+"%")
+	}
+	if req.CategoryId > 0 {
+		categoryIDs, err := s.categoryDescendants(req.CategoryId)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to query categories")
+		}
+		q = q.Where("id IN (?)", s.db.Table("product_categories").Select("product_id").Where("category_id IN ?", categoryIDs))
+	}
+
+	// 第 1 行为表头，数据从第 2 行开始
+	line := int32(1)
+	var products []model.Product
+	result := q.Preload("Categories").Preload("Skus").FindInBatches(&products, productExportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range products {
+			line++
+			if err := stream.Send(convertProductToRow(&products[i], line)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return status.Errorf(codes.Internal, "failed to export products: %v", result.Error)
+	}
+	return nil
+}
+
+// convertProductToRow 商品转为导出行，多规格商品的库存在规格上，不导出
+func convertProductToRow(product *model.Product, line int32) *pb.ProductRow {
+	row := &pb.ProductRow{
+		Line:        line,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       strconv.FormatFloat(product.Price, 'f', -1, 64),
+		MainImage:   product.MainImage,
+		Status:      productStatusNames[pb.ProductStatus(product.Status)],
+	}
+	if product.ExternalCode != nil {
+		row.ExternalCode = *product.ExternalCode
+	}
+	if len(product.Skus) == 0 {
+		row.Stock = strconv.Itoa(product.Stock)
+	}
+	ids := make([]string, 0, len(product.Categories))
+	for _, c := range product.Categories {
+		ids = append(ids, strconv.FormatUint(uint64(c.ID), 10))
+	}
+	row.CategoryIds = strings.Join(ids, ";")
+	return row
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"product-service/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StartOrderEventConsumer 消费订单事件，维护商品销量和库存
+func (s *ProductService) StartOrderEventConsumer(brokers []string, topic string) {
+	go func() {
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			Topic:    topic,
+			GroupID:  "product-service-group",
+			MinBytes: 1,
+			MaxBytes: 10e6,
+		})
+		defer r.Close()
+		fmt.Println("[Kafka] 商品服务订单事件消费者已启动...")
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			m, err := r.ReadMessage(ctx)
+			cancel()
+			if err != nil {
+				if ctx.Err() != context.DeadlineExceeded {
+					log.Println("Kafka 消费出错:", err)
+				}
+				continue
+			}
+			if err := s.handleOrderEvent(string(m.Value)); err != nil {
+				log.Printf("处理订单事件失败: %v, 消息: %s", err, string(m.Value))
+			}
+		}
+	}()
+}
+
+// handleOrderEvent 处理订单事件，格式如 "order_created|order_id=1|user_id=2|items=101:2,102:1:55"。
+// 下单时累计销量，取消时扣回销量并释放该订单占用的库存
+func (s *ProductService) handleOrderEvent(msg string) error {
+	event, fields := parseOrderMessage(msg)
+	var sign int
+	switch event {
+	case "order_created":
+		sign = 1
+	case "order_canceled":
+		sign = -1
+	default:
+		return nil
+	}
+	orderID, err := strconv.ParseInt(fields["order_id"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid order_id: %q", fields["order_id"])
+	}
+	items, err := parseOrderItems(fields["items"])
+	if err != nil {
+		return err
+	}
+	quantities := make(map[int64]int, len(items))
+	for _, item := range items {
+		quantities[item.ProductID] += item.Quantity
+	}
+	applied := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 同一订单的同一事件只处理一次
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.SalesEvent{OrderID: orderID, Event: event})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		for productID, quantity := range quantities {
+			if err := tx.Model(&model.Product{}).Where("id = ?", productID).
+				Update("sales_count", gorm.Expr("GREATEST(sales_count + ?, 0)", sign*quantity)).Error; err != nil {
+				return err
+			}
+		}
+		// 下单时库存已通过 ReserveStock 同步占用，取消时在此释放
+		if sign < 0 {
+			if _, err := releaseStock(tx, orderReference(orderID)); err != nil {
+				return err
+			}
+		}
+		applied = true
+		return nil
+	})
+	if err != nil || !applied || len(quantities) == 0 {
+		return err
+	}
+	// 销量和库存变化只影响详情，列表缓存依靠过期刷新，避免频繁失效
+	productIDs := make([]int64, 0, len(quantities))
+	for productID := range quantities {
+		productIDs = append(productIDs, productID)
+	}
+	if err := RedisClient.Del(context.Background(), productDetailKeys(productIDs)...).Err(); err != nil {
+		log.Printf("failed to delete product cache: %v", err)
+	}
+	return nil
+}
+
+func parseOrderMessage(msg string) (string, map[string]string) {
+	parts := strings.Split(msg, "|")
+	fields := make(map[string]string, len(parts))
+	for _, part := range parts[1:] {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			fields[k] = v
+		}
+	}
+	return parts[0], fields
+}
+
+// orderEventItem 订单事件中的订单项，无规格时 SkuID 为 0
+type orderEventItem struct {
+	ProductID int64
+	SkuID     int64
+	Quantity  int
+}
+
+// parseOrderItems 解析 "商品ID:数量[:规格ID]" 列表
+func parseOrderItems(items string) ([]orderEventItem, error) {
+	var result []orderEventItem
+	if items == "" {
+		return result, nil
+	}
+	for _, item := range strings.Split(items, ",") {
+		parts := strings.Split(item, ":")
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, fmt.Errorf("invalid order item: %q", item)
+		}
+		productID, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid product id: %q", parts[0])
+		}
+		quantity, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity: %q", parts[1])
+		}
+		var skuID int64
+		if len(parts) == 3 {
+			if skuID, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid sku id: %q", parts[2])
+			}
+		}
+		result = append(result, orderEventItem{ProductID: productID, SkuID: skuID, Quantity: quantity})
+	}
+	return result, nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"common/cache"
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// 商品列表查询分类，不同分类使用不同的缓存时间
+const (
+	listClassHome     = "home"     // 首页：无关键词、无分类
+	listClassCategory = "category" // 按分类浏览
+	listClassKeyword  = "keyword"  // 关键词查询
+)
+
+const (
+	// listVersionKey 商品列表缓存版本号，列表缓存 key 中带上版本号，
+	// 版本号自增后旧的列表缓存不再被读取，等待过期即可
+	listVersionKey = "product:list:version"
+
+	// listTagPrefix 列表缓存标签集合的前缀，各分类共享
+	listTagPrefix = "product:list:tag:"
+
+	// maxCachedListPage 只缓存前几页，更深的翻页命中率低
+	maxCachedListPage = 5
+)
+
+// defaultListCacheTTLs 各分类默认缓存时间，为 0 表示不缓存
+var defaultListCacheTTLs = map[string]time.Duration{
+	listClassHome:     2 * time.Minute,
+	listClassCategory: 2 * time.Minute,
+	listClassKeyword:  30 * time.Second,
+}
+
+var listCaches map[string]*cache.Cache
+
+func initListCaches(ttls map[string]time.Duration) {
+	listCaches = make(map[string]*cache.Cache, len(ttls))
+	for class, ttl := range ttls {
+		if ttl <= 0 {
+			continue
+		}
+		listCaches[class] = cache.New(RedisClient, cache.Options{
+			Name:      "product:list:" + class,
+			TTL:       ttl,
+			Jitter:    cacheTTLJitter,
+			TagPrefix: listTagPrefix,
+		})
+	}
+}
+
+// ConfigureListCache 按配置调整各分类的缓存时间，格式如 "home=2m,category=1m,keyword=0"
+func ConfigureListCache(spec string) error {
+	ttls := make(map[string]time.Duration, len(defaultListCacheTTLs))
+	for class, ttl := range defaultListCacheTTLs {
+		ttls[class] = ttl
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		class, value, ok := strings.Cut(item, "=")
+		if _, known := defaultListCacheTTLs[class]; !ok || !known {
+			return fmt.Errorf("invalid list cache ttl %q", item)
+		}
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid list cache ttl %q: %v", item, err)
+		}
+		ttls[class] = ttl
+	}
+	initListCaches(ttls)
+	return nil
+}
+
+// listQuery 归一化后的列表查询条件，字段顺序固定，序列化结果可作为缓存 key
+type listQuery struct {
+	Keyword    string `json:"k,omitempty"`
+	CategoryID int64  `json:"c,omitempty"`
+	SortBy     int32  `json:"s,omitempty"`
+	Page       int    `json:"p"`
+	PageSize   int    `json:"n"`
+}
+
+func newListQuery(req *pb.ListProductsRequest) listQuery {
+	q := listQuery{
+		// 关键词使用 LIKE 匹配，大小写不敏感
+		Keyword:    strings.ToLower(strings.TrimSpace(req.Keyword)),
+		CategoryID: req.CategoryId,
+		SortBy:     int32(req.SortBy),
+		Page:       int(req.Page),
+		PageSize:   int(req.PageSize),
+	}
+	if q.CategoryID < 0 {
+		q.CategoryID = 0
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 10
+	}
+	return q
+}
+
+func (q listQuery) class() string {
+	switch {
+	case q.Keyword != "":
+		return listClassKeyword
+	case q.CategoryID > 0:
+		return listClassCategory
+	default:
+		return listClassHome
+	}
+}
+
+// cacheKey 由版本号、分类和查询条件的哈希组成
+func (q listQuery) cacheKey(version int64) string {
+	bytes, _ := json.Marshal(q)
+	sum := sha1.Sum(bytes)
+	return fmt.Sprintf("product:list:v%d:%s:%s", version, q.class(), hex.EncodeToString(sum[:]))
+}
+
+// tags 列表缓存的标签：结果中的每个商品，以及会受商品字段修改影响的排序方式和关键词查询
+func (q listQuery) tags(resp *pb.ListProductsResponse) []string {
+	tags := make([]string, 0, len(resp.Products)+2)
+	for _, p := range resp.Products {
+		tags = append(tags, productListTag(p.Id))
+	}
+	if q.SortBy != int32(pb.ProductSortBy_PRODUCT_SORT_DEFAULT) && q.SortBy != int32(pb.ProductSortBy_PRODUCT_SORT_NEWEST) {
+		tags = append(tags, sortListTag(pb.ProductSortBy(q.SortBy)))
+	}
+	if q.Keyword != "" {
+		tags = append(tags, keywordListTag)
+	}
+	return tags
+}
+
+// keywordListTag 商品名称修改会影响所有关键词查询
+const keywordListTag = "keyword"
+
+func productListTag(productID int64) string {
+	return fmt.Sprintf("product:%d", productID)
+}
+
+func sortListTag(sortBy pb.ProductSortBy) string {
+	return fmt.Sprintf("sort:%d", sortBy)
+}
+
+// listCacheVersion 获取当前列表缓存版本号，读取失败时返回 0
+func listCacheVersion(ctx context.Context) int64 {
+	version, err := RedisClient.Get(ctx, listVersionKey).Int64()
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// invalidateProductLists 使所有商品列表缓存失效，用于新增、删除商品等影响范围无法确定的修改
+func invalidateProductLists(ctx context.Context) {
+	if err := RedisClient.Incr(ctx, listVersionKey).Err(); err != nil {
+		log.Printf("failed to bump product list cache version: %v", err)
+	}
+}
+
+// invalidateListTags 删除带有任一标签的列表缓存
+func invalidateListTags(ctx context.Context, tags ...string) {
+	// 标签集合各分类共享，使用任一分类的缓存操作即可
+	for _, c := range listCaches {
+		if err := c.InvalidateTags(ctx, tags...); err != nil {
+			log.Printf("failed to invalidate product list cache tags %v: %v", tags, err)
+		}
+		return
+	}
+}
+
+// GetCacheStats 返回商品详情和各分类列表缓存的命中统计
+func (s *ProductService) GetCacheStats(ctx context.Context, req *pb.GetCacheStatsRequest) (*pb.GetCacheStatsResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	caches := []*cache.Cache{detailCache}
+	for _, class := range []string{listClassHome, listClassCategory, listClassKeyword} {
+		if c, ok := listCaches[class]; ok {
+			caches = append(caches, c)
+		}
+	}
+	resp := &pb.GetCacheStatsResponse{}
+	for _, c := range caches {
+		st := c.Stats()
+		resp.Stats = append(resp.Stats, &pb.CacheStats{
+			Name:       st.Name,
+			Hits:       st.Hits,
+			Misses:     st.Misses,
+			Loads:      st.Loads,
+			Errors:     st.Errors,
+			HitRate:    st.HitRate(),
+			TtlSeconds: int64(st.TTL / time.Second),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	pb "common/proto/gen/product"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis 将 RedisClient 指向内存中的 Redis，测试结束后恢复
+func newTestRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	prev := RedisClient
+	RedisClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		RedisClient.Close()
+		RedisClient = prev
+	})
+	return mr
+}
+
+func TestNewListQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *pb.ListProductsRequest
+		want      listQuery
+		wantClass string
+	}{
+		{
+			name:      "defaults",
+			req:       &pb.ListProductsRequest{},
+			want:      listQuery{Page: 1, PageSize: 10},
+			wantClass: listClassHome,
+		},
+		{
+			name:      "negative category is ignored",
+			req:       &pb.ListProductsRequest{CategoryId: -1, Page: -2, PageSize: -3},
+			want:      listQuery{Page: 1, PageSize: 10},
+			wantClass: listClassHome,
+		},
+		{
+			name:      "category",
+			req:       &pb.ListProductsRequest{CategoryId: 3, Page: 2, PageSize: 20},
+			want:      listQuery{CategoryID: 3, Page: 2, PageSize: 20},
+			wantClass: listClassCategory,
+		},
+		{
+			name:      "keyword is normalized and wins over category",
+			req:       &pb.ListProductsRequest{Keyword: "  Phone ", CategoryId: 3, SortBy: pb.ProductSortBy_PRODUCT_SORT_PRICE_ASC},
+			want:      listQuery{Keyword: "phone", CategoryID: 3, SortBy: int32(pb.ProductSortBy_PRODUCT_SORT_PRICE_ASC), Page: 1, PageSize: 10},
+			wantClass: listClassKeyword,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newListQuery(tt.req)
+			if q != tt.want {
+				t.Errorf("newListQuery = %+v, want %+v", q, tt.want)
+			}
+			if class := q.class(); class != tt.wantClass {
+				t.Errorf("class = %s, want %s", class, tt.wantClass)
+			}
+		})
+	}
+}
+
+func TestListQueryCacheKey(t *testing.T) {
+	base := newListQuery(&pb.ListProductsRequest{Keyword: "phone"})
+	tests := []struct {
+		name     string
+		req      *pb.ListProductsRequest
+		version  int64
+		wantSame bool
+	}{
+		{name: "equivalent query", req: &pb.ListProductsRequest{Keyword: " PHONE", Page: 1, PageSize: 10}, wantSame: true},
+		{name: "different version", req: &pb.ListProductsRequest{Keyword: "phone"}, version: 1},
+		{name: "different page", req: &pb.ListProductsRequest{Keyword: "phone", Page: 2}},
+		{name: "different sort", req: &pb.ListProductsRequest{Keyword: "phone", SortBy: pb.ProductSortBy_PRODUCT_SORT_RATING}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := newListQuery(tt.req).cacheKey(tt.version)
+			if same := key == base.cacheKey(0); same != tt.wantSame {
+				t.Errorf("cacheKey = %s, same as %s = %v, want %v", key, base.cacheKey(0), same, tt.wantSame)
+			}
+		})
+	}
+	if key := base.cacheKey(3); !strings.HasPrefix(key, "product:list:v3:keyword:") {
+		t.Errorf("cacheKey = %s, want prefix product:list:v3:keyword:", key)
+	}
+}
+
+func TestListQueryTags(t *testing.T) {
+	resp := &pb.ListProductsResponse{Products: []*pb.Product{{Id: 1}, {Id: 2}}}
+	tests := []struct {
+		name string
+		req  *pb.ListProductsRequest
+		want []string
+	}{
+		{name: "default sort", req: &pb.ListProductsRequest{}, want: []string{"product:1", "product:2"}},
+		{name: "newest sort", req: &pb.ListProductsRequest{SortBy: pb.ProductSortBy_PRODUCT_SORT_NEWEST}, want: []string{"product:1", "product:2"}},
+		{name: "price sort", req: &pb.ListProductsRequest{SortBy: pb.ProductSortBy_PRODUCT_SORT_PRICE_DESC}, want: []string{"product:1", "product:2", "sort:2"}},
+		{name: "keyword", req: &pb.ListProductsRequest{Keyword: "phone"}, want: []string{"product:1", "product:2", keywordListTag}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := newListQuery(tt.req).tags(resp)
+			if strings.Join(tags, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("tags = %v, want %v", tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidateProductLists(t *testing.T) {
+	mr := newTestRedis(t)
+	ctx := context.Background()
+	q := newListQuery(&pb.ListProductsRequest{})
+
+	if version := listCacheVersion(ctx); version != 0 {
+		t.Fatalf("version = %d, want 0", version)
+	}
+	before := q.cacheKey(listCacheVersion(ctx))
+	invalidateProductLists(ctx)
+	if version := listCacheVersion(ctx); version != 1 {
+		t.Fatalf("version = %d, want 1", version)
+	}
+	if after := q.cacheKey(listCacheVersion(ctx)); after == before {
+		t.Errorf("cacheKey = %s after invalidation, want a new key", after)
+	}
+
+	// Redis 不可用时回退到版本 0，不影响查询
+	mr.Close()
+	if version := listCacheVersion(ctx); version != 0 {
+		t.Errorf("version = %d with redis down, want 0", version)
+	}
+}
+
+func TestConfigureListCache(t *testing.T) {
+	newTestRedis(t)
+	prev := listCaches
+	t.Cleanup(func() { listCaches = prev })
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{name: "defaults", spec: "", want: defaultListCacheTTLs},
+		{
+			name: "override and disable",
+			spec: "home=1m, keyword=0",
+			want: map[string]time.Duration{listClassHome: time.Minute, listClassCategory: 2 * time.Minute},
+		},
+		{name: "unknown class", spec: "search=1m", wantErr: true},
+		{name: "missing ttl", spec: "home", wantErr: true},
+		{name: "invalid ttl", spec: "home=soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConfigureListCache(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureListCache error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(listCaches) != len(tt.want) {
+				t.Fatalf("caches = %d, want %d", len(listCaches), len(tt.want))
+			}
+			for class, ttl := range tt.want {
+				c, ok := listCaches[class]
+				if !ok {
+					t.Errorf("class %s is not cached", class)
+					continue
+				}
+				if got := c.Stats().TTL; got != ttl {
+					t.Errorf("class %s ttl = %v, want %v", class, got, ttl)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"log"
+	"product-service/model"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxPriceHistoryPageSize     = 100
+	defaultPriceHistoryPageSize = 20
+
+	// DefaultPriceSchedulerInterval 定时调价的检查间隔
+	DefaultPriceSchedulerInterval = 30 * time.Second
+	// priceScheduleBatchSize 每次检查最多执行的调价数量
+	priceScheduleBatchSize = 100
+	// priceScheduleClockSkew 开始时间允许早于当前时间的误差
+	priceScheduleClockSkew = time.Minute
+)
+
+// SchedulePriceChange 创建定时调价
+func (s *ProductService) SchedulePriceChange(ctx context.Context, req *pb.SchedulePriceChangeRequest) (*pb.SchedulePriceChangeResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Price <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "price must be positive")
+	}
+	startAt, err := time.Parse(time.RFC3339, req.StartAt)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "start_at must be RFC3339")
+	}
+	if startAt.Before(time.Now().Add(-priceScheduleClockSkew)) {
+		return nil, status.Error(codes.InvalidArgument, "start_at must not be in the past")
+	}
+	var endAt *time.Time
+	if req.EndAt != "" {
+		t, err := time.Parse(time.RFC3339, req.EndAt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "end_at must be RFC3339")
+		}
+		if !t.After(startAt) {
+			return nil, status.Error(codes.InvalidArgument, "end_at must be after start_at")
+		}
+		endAt = &t
+	}
+	if req.SkuId > 0 {
+		err = s.db.Where("product_id = ?", req.ProductId).First(&model.Sku{}, req.SkuId).Error
+	} else {
+		err = s.db.First(&model.Product{}, req.ProductId).Error
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "product or sku not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to query product")
+	}
+
+	// 同一商品或规格的调价时段不能重叠，否则到期时无法确定应恢复的价格
+	q := s.db.Model(&model.PriceSchedule{}).
+		Where("product_id = ? AND sku_id = ? AND status IN ?", req.ProductId, req.SkuId,
+			[]int{int(pb.PriceScheduleStatus_PRICE_SCHEDULE_PENDING), int(pb.PriceScheduleStatus_PRICE_SCHEDULE_ACTIVE)})
+	if endAt != nil {
+		q = q.Where("start_at = ? OR (end_at IS NOT NULL AND start_at < ? AND end_at > ?)", startAt, *endAt, startAt)
+	} else {
+		q = q.Where("start_at = ?", startAt)
+	}
+	var count int64
+	if err := q.Count(&count).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query price schedules")
+	}
+	if count > 0 {
+		return nil, status.Error(codes.FailedPrecondition, "price schedule overlaps an existing one")
+	}
+
+	schedule := model.PriceSchedule{
+		ProductID:  uint(req.ProductId),
+		SkuID:      uint(req.SkuId),
+		Price:      req.Price,
+		StartAt:    startAt,
+		EndAt:      endAt,
+		Status:     int(pb.PriceScheduleStatus_PRICE_SCHEDULE_PENDING),
+		Note:       strings.TrimSpace(req.Note),
+		OperatorID: operatorID(ctx),
+	}
+	if err := s.db.Create(&schedule).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to create price schedule")
+	}
+	return &pb.SchedulePriceChangeResponse{Schedule: convertPriceScheduleToPB(&schedule)}, nil
+}
+
+// CancelPriceSchedule 取消定时调价，已生效的调价恢复原价
+func (s *ProductService) CancelPriceSchedule(ctx context.Context, req *pb.CancelPriceScheduleRequest) (*pb.CancelPriceScheduleResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var schedule model.PriceSchedule
+	changed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, req.ScheduleId).Error; err != nil {
+			return err
+		}
+		switch pb.PriceScheduleStatus(schedule.Status) {
+		case pb.PriceScheduleStatus_PRICE_SCHEDULE_PENDING:
+			return tx.Model(&schedule).Update("status", int(pb.PriceScheduleStatus_PRICE_SCHEDULE_CANCELED)).Error
+		case pb.PriceScheduleStatus_PRICE_SCHEDULE_ACTIVE:
+			var err error
+			changed, err = endPriceSchedule(tx, &schedule, pb.PriceScheduleStatus_PRICE_SCHEDULE_CANCELED, operatorID(ctx))
+			return err
+		}
+		return status.Error(codes.FailedPrecondition, "price schedule has already ended")
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.CancelPriceScheduleResponse{Success: false, Message: "price schedule not found"}, nil
+		}
+		if st, ok := status.FromError(err); ok {
+			return &pb.CancelPriceScheduleResponse{Success: false, Message: st.Message()}, nil
+		}
+		return &pb.CancelPriceScheduleResponse{Success: false, Message: "failed to cancel price schedule"}, nil
+	}
+	if changed {
+		s.priceChanged(ctx, &schedule)
+	}
+	return &pb.CancelPriceScheduleResponse{Success: true, Message: "price schedule canceled"}, nil
+}
+
+// ListPriceSchedules 商品的定时调价，按开始时间倒序
+func (s *ProductService) ListPriceSchedules(ctx context.Context, req *pb.ListPriceSchedulesRequest) (*pb.ListPriceSchedulesResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.ProductId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	q := s.db.Where("product_id = ?", req.ProductId)
+	if len(req.Statuses) > 0 {
+		statuses := make([]int, 0, len(req.Statuses))
+		for _, st := range req.Statuses {
+			statuses = append(statuses, int(st))
+		}
+		q = q.Where("status IN ?", statuses)
+	}
+	var schedules []model.PriceSchedule
+	if err := q.Order("start_at desc, id desc").Find(&schedules).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query price schedules")
+	}
+	resp := &pb.ListPriceSchedulesResponse{}
+	for i := range schedules {
+		resp.Schedules = append(resp.Schedules, convertPriceScheduleToPB(&schedules[i]))
+	}
+	return resp, nil
+}
+
+// GetPriceHistory 分页查询价格变动记录，最新的在前
+func (s *ProductService) GetPriceHistory(ctx context.Context, req *pb.GetPriceHistoryRequest) (*pb.GetPriceHistoryResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.ProductId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	page := int(req.Page)
+	pageSize := int(req.PageSize)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPriceHistoryPageSize
+	}
+	if pageSize > maxPriceHistoryPageSize {
+		pageSize = maxPriceHistoryPageSize
+	}
+
+	q := s.db.Model(&model.PriceChange{}).Where("product_id = ?", req.ProductId)
+	if req.SkuId > 0 {
+		q = q.Where("sku_id = ?", req.SkuId)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to count price changes")
+	}
+	var changes []model.PriceChange
+	if err := q.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&changes).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query price changes")
+	}
+	resp := &pb.GetPriceHistoryResponse{Total: int32(total)}
+	for i := range changes {
+		resp.Changes = append(resp.Changes, convertPriceChangeToPB(&changes[i]))
+	}
+	return resp, nil
+}
+
+// StartPriceScheduler 启动定时调价任务，多个实例同时运行时通过锁定调价记录保证每个调价只执行一次
+func (s *ProductService) StartPriceScheduler(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPriceSchedulerInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.runPriceSchedules(context.Background(), time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// runPriceSchedules 执行到期生效和到期恢复的调价
+func (s *ProductService) runPriceSchedules(ctx context.Context, now time.Time) {
+	var due []model.PriceSchedule
+	if err := s.db.Where("(status = ? AND start_at <= ?) OR (status = ? AND end_at <= ?)",
+		int(pb.PriceScheduleStatus_PRICE_SCHEDULE_PENDING), now,
+		int(pb.PriceScheduleStatus_PRICE_SCHEDULE_ACTIVE), now).
+		Order("start_at asc, id asc").Limit(priceScheduleBatchSize).Find(&due).Error; err != nil {
+		log.Printf("Failed to query price schedules: %v", err)
+		return
+	}
+	for i := range due {
+		changed, err := s.applyPriceSchedule(due[i].ID, now)
+		if err != nil {
+			log.Printf("Failed to apply price schedule %d: %v", due[i].ID, err)
+			continue
+		}
+		if changed {
+			s.priceChanged(ctx, &due[i])
+		}
+	}
+}
+
+// applyPriceSchedule 锁定调价记录后按状态生效或恢复原价，已被其他实例处理时不做修改；返回价格是否变化
+func (s *ProductService) applyPriceSchedule(id uint, now time.Time) (bool, error) {
+	changed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var schedule model.PriceSchedule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, id).Error; err != nil {
+			return err
+		}
+		switch {
+		case schedule.Status == int(pb.PriceScheduleStatus_PRICE_SCHEDULE_PENDING) && !schedule.StartAt.After(now):
+			if schedule.EndAt != nil && !schedule.EndAt.After(now) {
+				// 整个调价时段都已错过（如服务停机），不再生效
+				return tx.Model(&schedule).Update("status", int(pb.PriceScheduleStatus_PRICE_SCHEDULE_ENDED)).Error
+			}
+			c := model.PriceChange{
+				ProductID:  schedule.ProductID,
+				SkuID:      schedule.SkuID,
+				NewPrice:   schedule.Price,
+				Reason:     int(pb.PriceChangeReason_PRICE_REASON_SCHEDULE_START),
+				ScheduleID: schedule.ID,
+			}
+			err := trackProduct(tx, schedule.ProductID, 0, func() error {
+				return changePrice(tx, &c)
+			})
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					// 商品或规格已删除
+					return tx.Model(&schedule).Update("status", int(pb.PriceScheduleStatus_PRICE_SCHEDULE_CANCELED)).Error
+				}
+				return err
+			}
+			changed = c.OldPrice != c.NewPrice
+			// 没有结束时间的调价生效后即结束
+			next := pb.PriceScheduleStatus_PRICE_SCHEDULE_ACTIVE
+			if schedule.EndAt == nil {
+				next = pb.PriceScheduleStatus_PRICE_SCHEDULE_ENDED
+			}
+			return tx.Model(&schedule).Updates(map[string]interface{}{
+				"status":         int(next),
+				"original_price": c.OldPrice,
+			}).Error
+		case schedule.Status == int(pb.PriceScheduleStatus_PRICE_SCHEDULE_ACTIVE) && schedule.EndAt != nil && !schedule.EndAt.After(now):
+			var err error
+			changed, err = endPriceSchedule(tx, &schedule, pb.PriceScheduleStatus_PRICE_SCHEDULE_ENDED, 0)
+			return err
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// endPriceSchedule 结束已生效的调价并恢复原价。调价期间价格被手工修改过时保留修改后的价格；返回价格是否变化
+func endPriceSchedule(tx *gorm.DB, schedule *model.PriceSchedule, next pb.PriceScheduleStatus, operatorID int64) (bool, error) {
+	changed := false
+	current, err := lockPrice(tx, schedule.ProductID, schedule.SkuID)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return false, err
+	}
+	if err == nil && current == schedule.Price && current != schedule.OriginalPrice {
+		err := trackProduct(tx, schedule.ProductID, operatorID, func() error {
+			return changePrice(tx, &model.PriceChange{
+				ProductID:  schedule.ProductID,
+				SkuID:      schedule.SkuID,
+				NewPrice:   schedule.OriginalPrice,
+				Reason:     int(pb.PriceChangeReason_PRICE_REASON_SCHEDULE_END),
+				ScheduleID: schedule.ID,
+				OperatorID: operatorID,
+			})
+		})
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, tx.Model(schedule).Update("status", int(next)).Error
+}
+
+// priceChanged 价格变化后更新搜索索引并清理缓存，商品本身的价格还会影响按价格排序的列表
+func (s *ProductService) priceChanged(ctx context.Context, schedule *model.PriceSchedule) {
+	productID := int64(schedule.ProductID)
+	if schedule.SkuID > 0 {
+		invalidateProduct(ctx, []int64{productID})
+		return
+	}
+	s.indexProduct(productID)
+	invalidateProduct(ctx, []int64{productID}, priceListTags()...)
+}
+
+// priceListTags 按价格排序的商品列表缓存标签
+func priceListTags() []string {
+	return []string{
+		sortListTag(pb.ProductSortBy_PRODUCT_SORT_PRICE_ASC),
+		sortListTag(pb.ProductSortBy_PRODUCT_SORT_PRICE_DESC),
+	}
+}
+
+// lockPrice 锁定商品或规格并返回当前价格，不存在时返回 NotFound
+func lockPrice(tx *gorm.DB, productID, skuID uint) (float64, error) {
+	if skuID > 0 {
+		var sku model.Sku
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "price").
+			Where("product_id = ?", productID).First(&sku, skuID).Error
+		if err == gorm.ErrRecordNotFound {
+			return 0, status.Error(codes.NotFound, "sku not found")
+		}
+		return sku.Price, err
+	}
+	var product model.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "price").First(&product, productID).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, status.Error(codes.NotFound, "product not found")
+	}
+	return product.Price, err
+}
+
+// changePrice 在事务中将商品或规格的价格改为 c.NewPrice 并写入变动记录，c.OldPrice 由修改前的价格填充；价格未变化时不做修改
+func changePrice(tx *gorm.DB, c *model.PriceChange) error {
+	current, err := lockPrice(tx, c.ProductID, c.SkuID)
+	if err != nil {
+		return err
+	}
+	c.OldPrice = current
+	if current == c.NewPrice {
+		return nil
+	}
+	var target *gorm.DB
+	if c.SkuID > 0 {
+		target = tx.Model(&model.Sku{}).Where("id = ?", c.SkuID)
+	} else {
+		target = tx.Model(&model.Product{}).Where("id = ?", c.ProductID)
+	}
+	if err := target.Update("price", c.NewPrice).Error; err != nil {
+		return err
+	}
+	return tx.Create(c).Error
+}
+
+// initPrice 记录新建商品或规格的价格
+func initPrice(tx *gorm.DB, productID, skuID uint, price float64, operatorID int64) error {
+	return tx.Create(&model.PriceChange{
+		ProductID:  productID,
+		SkuID:      skuID,
+		NewPrice:   price,
+		Reason:     int(pb.PriceChangeReason_PRICE_REASON_INITIAL),
+		OperatorID: operatorID,
+	}).Error
+}
+
+func convertPriceChangeToPB(c *model.PriceChange) *pb.PriceChange {
+	return &pb.PriceChange{
+		Id:         int64(c.ID),
+		ProductId:  int64(c.ProductID),
+		SkuId:      int64(c.SkuID),
+		OldPrice:   c.OldPrice,
+		NewPrice:   c.NewPrice,
+		Reason:     pb.PriceChangeReason(c.Reason),
+		ScheduleId: int64(c.ScheduleID),
+		OperatorId: c.OperatorID,
+		CreatedAt:  c.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func convertPriceScheduleToPB(schedule *model.PriceSchedule) *pb.PriceSchedule {
+	result := &pb.PriceSchedule{
+		Id:            int64(schedule.ID),
+		ProductId:     int64(schedule.ProductID),
+		SkuId:         int64(schedule.SkuID),
+		Price:         schedule.Price,
+		StartAt:       schedule.StartAt.Format(time.RFC3339),
+		Status:        pb.PriceScheduleStatus(schedule.Status),
+		OriginalPrice: schedule.OriginalPrice,
+		Note:          schedule.Note,
+		OperatorId:    schedule.OperatorID,
+		CreatedAt:     schedule.CreatedAt.Format(time.RFC3339),
+	}
+	if schedule.EndAt != nil {
+		result.EndAt = schedule.EndAt.Format(time.RFC3339)
+	}
+	return result
+}
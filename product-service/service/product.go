@@ -1,39 +1,113 @@
 package service
 
 import (
+	"common/cache"
+	"common/middleware"
+	pbOrder "common/proto/gen/order"
 	pb "common/proto/gen/product"
 	"context"
-	"encoding/json"
-	"fmt"
+	"log"
 	"product-service/model"
+	"product-service/search"
 	"strings"
-	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// maxBatchGetProducts 批量获取商品的数量上限
+const maxBatchGetProducts = 100
+
 type ProductService struct {
 	pb.UnimplementedProductServiceServer
-	db *gorm.DB
+	db          *gorm.DB
+	index       *search.Index
+	orderClient pbOrder.OrderServiceClient
 }
 
-func NewProductService(db *gorm.DB) *ProductService {
-	return &ProductService{db: db}
+func NewProductService(db *gorm.DB, index *search.Index, orderClient pbOrder.OrderServiceClient) *ProductService {
+	return &ProductService{db: db, index: index, orderClient: orderClient}
 }
 
 func (s *ProductService) CreateProduct(ctx context.Context, req *pb.CreateProductRequest) (*pb.CreateProductResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if _, ok := pb.ProductStatus_name[int32(req.Status)]; !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid product status")
+	}
+	if req.LowStockThreshold < 0 {
+		return nil, status.Error(codes.InvalidArgument, "low stock threshold must not be negative")
+	}
+	categories, err := s.loadCategories(req.CategoryIds)
+	if err != nil {
+		return nil, err
+	}
+	var skus []model.Sku
+	for _, in := range req.Skus {
+		sku, err := newSkuModel(in)
+		if err != nil {
+			return nil, err
+		}
+		skus = append(skus, sku)
+	}
 	product := model.Product{
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
-		Stock:       int(req.Stock),
-		MainImage:   req.MainImage,
+		Name:              req.Name,
+		Description:       req.Description,
+		Price:             req.Price,
+		Stock:             int(req.Stock),
+		MainImage:         req.MainImage,
+		Status:            int(req.Status),
+		Categories:        categories,
+		LowStockThreshold: int(req.LowStockThreshold),
+		Skus:              skus,
+	}
+	if code := strings.TrimSpace(req.ExternalCode); code != "" {
+		var count int64
+		s.db.Model(&model.Product{}).Where("external_code = ?", code).Count(&count)
+		if count > 0 {
+			return nil, status.Error(codes.AlreadyExists, "external code already exists")
+		}
+		product.ExternalCode = &code
 	}
-	if err := s.db.Create(&product).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if err := initPrice(tx, product.ID, 0, product.Price, operatorID(ctx)); err != nil {
+			return err
+		}
+		// 初始库存放入默认仓库，多规格商品按规格记录
+		if len(product.Skus) == 0 {
+			if err := initStock(tx, product.ID, 0, product.Stock, operatorID(ctx)); err != nil {
+				return err
+			}
+		}
+		for _, sku := range product.Skus {
+			if err := initPrice(tx, product.ID, sku.ID, sku.Price, operatorID(ctx)); err != nil {
+				return err
+			}
+			if err := initStock(tx, product.ID, sku.ID, sku.Stock, operatorID(ctx)); err != nil {
+				return err
+			}
+		}
+		return recordProductChange(tx, product.ID, operatorID(ctx), nil)
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
 		return nil, status.Error(codes.Internal, "failed to create product")
 	}
+	if product.Status == int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE) {
+		s.index.Put(int64(product.ID), newSearchDocument(&product))
+	}
+	addToProductFilter(ctx, int64(product.ID))
+	// 清理可能存在的空值缓存
+	RedisClient.Del(ctx, productDetailKey(int64(product.ID)))
+	invalidateProductLists(ctx)
 	return &pb.CreateProductResponse{
 		ProductId: int64(product.ID),
 		Message:   "product created successfully",
@@ -41,53 +115,136 @@ func (s *ProductService) CreateProduct(ctx context.Context, req *pb.CreateProduc
 }
 
 func (s *ProductService) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.GetProductResponse, error) {
-	// 1. 先查 Redis
-	cacheKey := fmt.Sprintf("product:detail:%d", req.ProductId)
-	val, err := RedisClient.Get(ctx, cacheKey).Result()
-	if err == nil && val != "" {
-		var cachedProduct pb.Product
-		if json.Unmarshal([]byte(val), &cachedProduct) == nil {
-			return &pb.GetProductResponse{Product: &cachedProduct}, nil
-		}
+	// 1. 布隆过滤器拦截一定不存在的商品ID
+	if !productMayExist(ctx, req.ProductId) {
+		return nil, status.Error(codes.NotFound, "product not found")
 	}
 
-	// 2. 查数据库
-	var product model.Product
-	if err := s.db.First(&product, req.ProductId).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	// 2. 查 Redis，未命中时查数据库并回写；不存在的商品缓存空值
+	var pbProduct pb.Product
+	err := detailCache.Get(ctx, productDetailKey(req.ProductId), &pbProduct, func(ctx context.Context) (interface{}, error) {
+		var product model.Product
+		if err := preloadProductDetails(s.db).First(&product, req.ProductId).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, cache.ErrNotFound
+			}
+			return nil, err
+		}
+		return convertProductModelToPB(&product), nil
+	})
+	if err != nil {
+		if err == cache.ErrNotFound {
 			return nil, status.Error(codes.NotFound, "product not found")
 		}
 		return nil, status.Error(codes.Internal, "failed to query product")
 	}
+	if !productVisible(ctx, &pbProduct) {
+		return nil, status.Error(codes.NotFound, "product not found")
+	}
+	return &pb.GetProductResponse{Product: &pbProduct}, nil
+}
+
+// BatchGetProducts 批量获取商品：先 MGET 读取缓存，未命中的商品一次查询数据库
+func (s *ProductService) BatchGetProducts(ctx context.Context, req *pb.BatchGetProductsRequest) (*pb.BatchGetProductsResponse, error) {
+	// 去重并保持请求顺序
+	seen := make(map[int64]bool, len(req.ProductIds))
+	ids := make([]int64, 0, len(req.ProductIds))
+	for _, id := range req.ProductIds {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxBatchGetProducts {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d products per request", maxBatchGetProducts)
+	}
 
-	// 3. 写入 Redis
-	pbProduct := &pb.Product{
-		Id:          int64(product.ID),
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		Stock:       int32(product.Stock),
-		MainImage:   product.MainImage,
+	products := make([]*pb.Product, len(ids))
+	misses, notFound := detailCache.GetMany(ctx, productDetailKeys(ids), func(i int) interface{} {
+		products[i] = &pb.Product{}
+		return products[i]
+	})
+	missing := make(map[int64]bool, len(notFound))
+	for _, i := range notFound {
+		products[i] = nil
+		missing[ids[i]] = true
+	}
+
+	if len(misses) > 0 {
+		missIDs := make([]int64, 0, len(misses))
+		for _, i := range misses {
+			products[i] = nil
+			missIDs = append(missIDs, ids[i])
+		}
+		var found []model.Product
+		if err := preloadProductDetails(s.db).Where("id IN ?", missIDs).Find(&found).Error; err != nil {
+			return nil, status.Error(codes.Internal, "failed to query products")
+		}
+		loaded := make(map[int64]*pb.Product, len(found))
+		values := make(map[string]interface{}, len(found))
+		for i := range found {
+			p := convertProductModelToPB(&found[i])
+			loaded[p.Id] = p
+			values[productDetailKey(p.Id)] = p
+		}
+		var notFoundKeys []string
+		for _, i := range misses {
+			if p, ok := loaded[ids[i]]; ok {
+				products[i] = p
+			} else {
+				missing[ids[i]] = true
+				notFoundKeys = append(notFoundKeys, productDetailKey(ids[i]))
+			}
+		}
+		if err := detailCache.SetMany(ctx, values); err != nil {
+			log.Printf("failed to cache products: %v", err)
+		}
+		if err := detailCache.SetNotFound(ctx, notFoundKeys...); err != nil {
+			log.Printf("failed to cache missing products: %v", err)
+		}
 	}
-	bytes, _ := json.Marshal(pbProduct)
-	RedisClient.Set(ctx, cacheKey, bytes, 5*time.Minute)
 
-	return &pb.GetProductResponse{Product: pbProduct}, nil
+	resp := &pb.BatchGetProductsResponse{}
+	for i, id := range ids {
+		if missing[id] || (products[i] != nil && !productVisible(ctx, products[i])) {
+			resp.MissingIds = append(resp.MissingIds, id)
+		} else if products[i] != nil {
+			resp.Products = append(resp.Products, products[i])
+		}
+	}
+	return resp, nil
 }
 
 func (s *ProductService) ListProducts(ctx context.Context, req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
-	// 只缓存无关键词、第一页的商品列表
-	cacheKey := "product:list:page:1:size:10"
-	if strings.TrimSpace(req.Keyword) == "" && (req.Page == 1 || req.Page == 0) && (req.PageSize == 10 || req.PageSize == 0) {
-		val, err := RedisClient.Get(ctx, cacheKey).Result()
-		if err == nil && val != "" {
-			var cachedResp pb.ListProductsResponse
-			if json.Unmarshal([]byte(val), &cachedResp) == nil {
-				return &cachedResp, nil
-			}
+	// 按状态查询仅管理员可用，不缓存
+	if len(req.Statuses) > 0 {
+		if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+			return nil, err
+		}
+		return s.listProducts(req)
+	}
+	// 前几页按查询条件缓存，key 带版本号以便整体失效
+	q := newListQuery(req)
+	c, ok := listCaches[q.class()]
+	if !ok || q.Page > maxCachedListPage {
+		return s.listProducts(req)
+	}
+	var resp pb.ListProductsResponse
+	err := c.GetTagged(ctx, q.cacheKey(listCacheVersion(ctx)), &resp, func(ctx context.Context) (interface{}, []string, error) {
+		resp, err := s.listProducts(req)
+		if err != nil {
+			return nil, nil, err
 		}
+		return resp, q.tags(resp), nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &resp, nil
+}
 
+// listProducts 从数据库查询商品列表
+func (s *ProductService) listProducts(req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
 	var products []model.Product
 	var total int64
 	page := int(req.Page)
@@ -100,40 +257,47 @@ func (s *ProductService) ListProducts(ctx context.Context, req *pb.ListProductsR
 	}
 	offset := (page - 1) * pageSize
 	dbQuery := s.db.Model(&model.Product{})
+	if len(req.Statuses) > 0 {
+		statuses := make([]int, 0, len(req.Statuses))
+		for _, st := range req.Statuses {
+			statuses = append(statuses, int(st))
+		}
+		dbQuery = dbQuery.Where("status IN ?", statuses)
+	} else {
+		dbQuery = dbQuery.Where("status = ?", int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE))
+	}
 	if strings.TrimSpace(req.Keyword) != "" {
 		kw := "%" + strings.TrimSpace(req.Keyword) + "%"
 		dbQuery = dbQuery.Where("name LIKE ?", kw)
 	}
+	if req.CategoryId > 0 {
+		// 分类过滤包含所有子分类
+		categoryIDs, err := s.categoryDescendants(req.CategoryId)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to query categories")
+		}
+		dbQuery = dbQuery.Where("id IN (?)", s.db.Table("product_categories").Select("product_id").Where("category_id IN ?", categoryIDs))
+	}
 	if err := dbQuery.Count(&total).Error; err != nil {
 		return nil, status.Error(codes.Internal, "failed to count products")
 	}
-	if err := dbQuery.Limit(pageSize).Offset(offset).Order("id desc").Find(&products).Error; err != nil {
+	if err := preloadProductDetails(dbQuery).Limit(pageSize).Offset(offset).Order(productOrderClause(req.SortBy)).Find(&products).Error; err != nil {
 		return nil, status.Error(codes.Internal, "failed to list products")
 	}
 	var pbProducts []*pb.Product
 	for _, p := range products {
-		pbProducts = append(pbProducts, &pb.Product{
-			Id:          int64(p.ID),
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-			Stock:       int32(p.Stock),
-			MainImage:   p.MainImage,
-		})
+		pbProducts = append(pbProducts, convertProductModelToPB(&p))
 	}
-	resp := &pb.ListProductsResponse{
+	return &pb.ListProductsResponse{
 		Products: pbProducts,
 		Total:    int32(total),
-	}
-	// 写入缓存
-	if strings.TrimSpace(req.Keyword) == "" && page == 1 && pageSize == 10 {
-		bytes, _ := json.Marshal(resp)
-		RedisClient.Set(ctx, cacheKey, bytes, 2*time.Minute)
-	}
-	return resp, nil
+	}, nil
 }
 
 func (s *ProductService) UpdateProduct(ctx context.Context, req *pb.UpdateProductRequest) (*pb.UpdateProductResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
 	var product model.Product
 	if err := s.db.First(&product, req.ProductId).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -141,30 +305,194 @@ func (s *ProductService) UpdateProduct(ctx context.Context, req *pb.UpdateProduc
 		}
 		return &pb.UpdateProductResponse{Success: false, Message: "failed to query product"}, nil
 	}
-	if req.Name != "" {
+	// 名称和价格的修改会影响关键词查询和按价格排序的列表
+	var listTags []string
+	if req.Name != "" && req.Name != product.Name {
 		product.Name = req.Name
+		listTags = append(listTags, keywordListTag)
 	}
 	if req.Description != "" {
 		product.Description = req.Description
 	}
-	if req.Price != 0 {
-		product.Price = req.Price
-	}
-	if req.Stock != 0 {
-		product.Stock = int(req.Stock)
+	if req.Price != 0 && req.Price != product.Price {
+		listTags = append(listTags, priceListTags()...)
 	}
 	if req.MainImage != "" {
 		product.MainImage = req.MainImage
 	}
-	if err := s.db.Save(&product).Error; err != nil {
+	if req.Stock != nil {
+		if *req.Stock < 0 {
+			return nil, status.Error(codes.InvalidArgument, "stock cannot be negative")
+		}
+		// 多规格商品的总库存是各规格库存之和，只能按规格修改
+		var skuCount int64
+		if err := s.db.Model(&model.Sku{}).Where("product_id = ?", product.ID).Count(&skuCount).Error; err != nil {
+			return &pb.UpdateProductResponse{Success: false, Message: "failed to query skus"}, nil
+		}
+		if skuCount > 0 {
+			return nil, status.Error(codes.InvalidArgument, "product has skus, set stock on the sku")
+		}
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, product.ID, operatorID(ctx), func() error {
+			// 价格和库存单独修改，分别记录变动；事件序号由 createProductEvent 维护
+			if err := tx.Omit("Stock", "Price", "EventSeq").Save(&product).Error; err != nil {
+				return err
+			}
+			if req.Price != 0 {
+				if err := changePrice(tx, &model.PriceChange{
+					ProductID:  product.ID,
+					NewPrice:   req.Price,
+					Reason:     int(pb.PriceChangeReason_PRICE_REASON_MANUAL),
+					OperatorID: operatorID(ctx),
+				}); err != nil {
+					return err
+				}
+			}
+			if req.Stock == nil {
+				return nil
+			}
+			// 兼容旧接口：库存不再直接覆盖，差额全部记在默认仓库，按仓库调整请使用 AdjustStock
+			return setProductStock(tx, product.ID, int(*req.Stock), "set by UpdateProduct", operatorID(ctx))
+		})
+	})
+	if err != nil {
 		return &pb.UpdateProductResponse{Success: false, Message: "failed to update product"}, nil
 	}
+	s.indexProduct(req.ProductId)
+	invalidateProduct(ctx, []int64{req.ProductId}, listTags...)
 	return &pb.UpdateProductResponse{Success: true, Message: "product updated successfully"}, nil
 }
 
+// SetProductStatus 修改商品状态，上架和下架会影响商品列表和搜索结果
+func (s *ProductService) SetProductStatus(ctx context.Context, req *pb.SetProductStatusRequest) (*pb.SetProductStatusResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if _, ok := pb.ProductStatus_name[int32(req.Status)]; !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid product status")
+	}
+	var product model.Product
+	if err := s.db.Select("id", "status").First(&product, req.ProductId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.SetProductStatusResponse{Success: false, Message: "product not found"}, nil
+		}
+		return &pb.SetProductStatusResponse{Success: false, Message: "failed to query product"}, nil
+	}
+	if product.Status == int(req.Status) {
+		return &pb.SetProductStatusResponse{Success: true, Message: "product status unchanged"}, nil
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, product.ID, operatorID(ctx), func() error {
+			return tx.Model(&product).Update("status", int(req.Status)).Error
+		})
+	})
+	if err != nil {
+		return &pb.SetProductStatusResponse{Success: false, Message: "failed to update product status"}, nil
+	}
+	s.indexProduct(req.ProductId)
+	invalidateProduct(ctx, []int64{req.ProductId})
+	invalidateProductLists(ctx)
+	return &pb.SetProductStatusResponse{Success: true, Message: "product status updated"}, nil
+}
+
 func (s *ProductService) DeleteProduct(ctx context.Context, req *pb.DeleteProductRequest) (*pb.DeleteProductResponse, error) {
-	if err := s.db.Delete(&model.Product{}, req.ProductId).Error; err != nil {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, uint(req.ProductId), operatorID(ctx), func() error {
+			// 释放外部编码，之后可以重新导入同编码的商品
+			if err := tx.Model(&model.Product{}).Where("id = ?", req.ProductId).Update("external_code", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&model.Product{}, req.ProductId).Error
+		})
+	})
+	if err != nil {
 		return &pb.DeleteProductResponse{Success: false, Message: "failed to delete product"}, nil
 	}
+	s.removeFromIndex(req.ProductId)
+	invalidateProduct(ctx, []int64{req.ProductId})
+	invalidateProductLists(ctx)
 	return &pb.DeleteProductResponse{Success: true, Message: "product deleted successfully"}, nil
 }
+
+// setProductStock 将无规格商品的总库存设置为 target，差额在默认仓库记录为手工调整
+func setProductStock(tx *gorm.DB, productID uint, target int, note string, operatorID int64) error {
+	var current model.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "stock").First(&current, productID).Error; err != nil {
+		return err
+	}
+	if target == current.Stock {
+		return nil
+	}
+	return changeStock(tx, &model.StockMovement{
+		ProductID:  productID,
+		Delta:      target - current.Stock,
+		Reason:     int(pb.StockReason_STOCK_REASON_ADJUSTMENT),
+		Note:       note,
+		OperatorID: operatorID,
+	}, false)
+}
+
+// productVisible 未上架的商品只对管理员可见
+func productVisible(ctx context.Context, product *pb.Product) bool {
+	return product.Status == pb.ProductStatus_PRODUCT_STATUS_ACTIVE || middleware.HasRole(ctx, middleware.RoleAdmin)
+}
+
+// productOrderClause 商品列表排序，以 ID 作为次级排序保证分页稳定
+func productOrderClause(sortBy pb.ProductSortBy) string {
+	switch sortBy {
+	case pb.ProductSortBy_PRODUCT_SORT_PRICE_ASC:
+		return "price asc, id desc"
+	case pb.ProductSortBy_PRODUCT_SORT_PRICE_DESC:
+		return "price desc, id desc"
+	case pb.ProductSortBy_PRODUCT_SORT_NEWEST:
+		// ID 自增，按 ID 倒序即按上架时间倒序，可直接使用主键
+		return "id desc"
+	case pb.ProductSortBy_PRODUCT_SORT_BEST_SELLING:
+		return "sales_count desc, id desc"
+	case pb.ProductSortBy_PRODUCT_SORT_RATING:
+		return "rating_avg desc, rating_count desc, id desc"
+	default:
+		return "id desc"
+	}
+}
+
+// 工具函数：模型转pb
+func convertProductModelToPB(product *model.Product) *pb.Product {
+	var categoryIDs []int64
+	for _, c := range product.Categories {
+		categoryIDs = append(categoryIDs, int64(c.ID))
+	}
+	var skus []*pb.Sku
+	for i := range product.Skus {
+		skus = append(skus, convertSkuModelToPB(&product.Skus[i]))
+	}
+	var images []*pb.ProductImage
+	for i := range product.Images {
+		images = append(images, convertProductImageToPB(&product.Images[i]))
+	}
+	var externalCode string
+	if product.ExternalCode != nil {
+		externalCode = *product.ExternalCode
+	}
+	return &pb.Product{
+		Id:                int64(product.ID),
+		Name:              product.Name,
+		Description:       product.Description,
+		Price:             product.Price,
+		Stock:             int32(product.Stock),
+		MainImage:         product.MainImage,
+		CategoryIds:       categoryIDs,
+		Skus:              skus,
+		SalesCount:        int32(product.SalesCount),
+		RatingAvg:         product.RatingAvg,
+		RatingCount:       int32(product.RatingCount),
+		Images:            images,
+		ExternalCode:      externalCode,
+		Status:            pb.ProductStatus(product.Status),
+		LowStockThreshold: int32(product.LowStockThreshold),
+	}
+}
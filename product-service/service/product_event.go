@@ -0,0 +1,302 @@
+package service
+
+import (
+	pb "common/proto/gen/product"
+	"context"
+	"encoding/json"
+	"log"
+	"product-service/model"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// 商品事件类型
+const (
+	productEventCreated      = "product_created"
+	productEventUpdated      = "product_updated"
+	productEventDeleted      = "product_deleted"
+	productEventStockChanged = "product_stock_changed"
+	productEventLowStock     = "product_low_stock"
+	productEventOutOfStock   = "product_out_of_stock"
+)
+
+const (
+	// DefaultProductEventInterval 检查发件箱的默认间隔
+	DefaultProductEventInterval = time.Second
+	// productEventBatchSize 每次发送的事件数量上限
+	productEventBatchSize = 100
+	// productEventRetention 已发送事件在发件箱中的保留时间
+	productEventRetention = 7 * 24 * time.Hour
+	// productEventLockKey 同一时间只有一个实例发送，保证同一商品的事件按顺序发送
+	productEventLockKey = "lock:product-events"
+	productEventLockTTL = 30 * time.Second
+)
+
+// productEventJSON 快照和流水的编码方式，字段名与 proto 一致并输出零值
+var productEventJSON = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// productEventMessage 发送到 Kafka 的商品事件，至少发送一次，消费者可以按 ID 去重
+type productEventMessage struct {
+	ID         uint            `json:"id"`
+	Event      string          `json:"event"`
+	ProductID  uint            `json:"product_id"`
+	Seq        uint64          `json:"seq"` // 同一商品的事件序号递增，消费者可以据此丢弃过期事件
+	OperatorID int64           `json:"operator_id"`
+	OccurredAt string          `json:"occurred_at"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Movement   json.RawMessage `json:"movement,omitempty"`
+}
+
+// stockLevel 库存变化和预警事件前后的库存
+type stockLevel struct {
+	Stock             int `json:"stock"`               // 商品或规格在所有仓库的总库存
+	ProductStock      int `json:"product_stock"`       // 商品的总库存，多规格商品为各规格之和
+	WarehouseStock    int `json:"warehouse_stock"`     // 流水对应仓库的库存
+	LowStockThreshold int `json:"low_stock_threshold"` // 商品的低库存预警阈值
+}
+
+// trackProduct 在事务中执行 fn，比较前后快照记录商品新增、修改或删除事件，没有变化时不记录。
+// 库存变化另有事件，销量和评分的变化不记录事件
+func trackProduct(tx *gorm.DB, productID uint, operatorID int64, fn func() error) error {
+	before, err := productSnapshot(tx, productID)
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return recordProductChange(tx, productID, operatorID, before)
+}
+
+// recordProductChange 按 before 和商品当前的快照记录事件，before 为 nil 表示新增
+func recordProductChange(tx *gorm.DB, productID uint, operatorID int64, before *pb.Product) error {
+	after, err := productSnapshot(tx, productID)
+	if err != nil {
+		return err
+	}
+	event := model.ProductEvent{ProductID: productID, OperatorID: operatorID}
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case before == nil:
+		event.Event = productEventCreated
+	case after == nil:
+		event.Event = productEventDeleted
+	case proto.Equal(before, after):
+		return nil
+	default:
+		event.Event = productEventUpdated
+	}
+	if before != nil {
+		data, err := productEventJSON.Marshal(before)
+		if err != nil {
+			return err
+		}
+		event.Before = string(data)
+	}
+	if after != nil {
+		data, err := productEventJSON.Marshal(after)
+		if err != nil {
+			return err
+		}
+		event.After = string(data)
+	}
+	return createProductEvent(tx, &event)
+}
+
+// createProductEvent 分配序号并写入事件。递增商品的事件序号会锁住商品行直到事务结束，
+// 同一商品的事件因此按序号顺序提交，ID 顺序与序号顺序一致
+func createProductEvent(tx *gorm.DB, event *model.ProductEvent) error {
+	if err := tx.Unscoped().Model(&model.Product{}).Where("id = ?", event.ProductID).
+		UpdateColumn("event_seq", gorm.Expr("event_seq + 1")).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&model.Product{}).Select("event_seq").Where("id = ?", event.ProductID).
+		Scan(&event.Seq).Error; err != nil {
+		return err
+	}
+	return tx.Create(event).Error
+}
+
+// recordStockEvent 记录库存变化事件，商品或规格的总库存越过预警阈值时再记录一条预警事件。
+// m 为已写入的流水，warehouseBefore 为该仓库变化前的库存
+func recordStockEvent(tx *gorm.DB, m *model.StockMovement, warehouseBefore int) error {
+	total, err := totalStock(tx, m.ProductID, m.SkuID)
+	if err != nil {
+		return err
+	}
+	productTotal := total
+	if m.SkuID > 0 {
+		if err := tx.Model(&model.Sku{}).Select("COALESCE(SUM(stock), 0)").Where("product_id = ?", m.ProductID).
+			Scan(&productTotal).Error; err != nil {
+			return err
+		}
+	}
+	var threshold int
+	if err := tx.Model(&model.Product{}).Select("low_stock_threshold").Where("id = ?", m.ProductID).
+		Scan(&threshold).Error; err != nil {
+		return err
+	}
+	before, _ := json.Marshal(stockLevel{
+		Stock:             total - m.Delta,
+		ProductStock:      productTotal - m.Delta,
+		WarehouseStock:    warehouseBefore,
+		LowStockThreshold: threshold,
+	})
+	after, _ := json.Marshal(stockLevel{
+		Stock:             total,
+		ProductStock:      productTotal,
+		WarehouseStock:    m.StockAfter,
+		LowStockThreshold: threshold,
+	})
+	movement, err := productEventJSON.Marshal(convertStockMovementToPB(m))
+	if err != nil {
+		return err
+	}
+	events := []string{productEventStockChanged}
+	if alert := stockAlertEvent(total-m.Delta, total, threshold); alert != "" {
+		events = append(events, alert)
+	}
+	for _, event := range events {
+		if err := createProductEvent(tx, &model.ProductEvent{
+			Event:      event,
+			ProductID:  m.ProductID,
+			Before:     string(before),
+			After:      string(after),
+			Movement:   string(movement),
+			OperatorID: m.OperatorID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stockAlertEvent 库存从 before 变为 after 时的预警事件：降到 0 及以下为售罄，降到阈值及以下为低库存，
+// 没有越过阈值时返回空，已低于阈值的库存继续减少不重复预警
+func stockAlertEvent(before, after, threshold int) string {
+	switch {
+	case before > 0 && after <= 0:
+		return productEventOutOfStock
+	case before > threshold && after <= threshold && after > 0:
+		return productEventLowStock
+	}
+	return ""
+}
+
+// productSnapshot 商品当前的完整信息，不存在或已删除时返回 nil
+func productSnapshot(tx *gorm.DB, productID uint) (*pb.Product, error) {
+	var product model.Product
+	err := preloadProductDetails(tx).First(&product, productID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return convertProductModelToPB(&product), nil
+}
+
+// StartProductEventPublisher 启动商品事件发送任务。事件与商品变更在同一事务中写入发件箱，
+// 发送成功（所有副本确认）后才标记为已发送，失败时下次重试，保证每个事件至少发送一次
+func (s *ProductService) StartProductEventPublisher(brokers []string, topic string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultProductEventInterval
+	}
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{}, // 以商品ID为 key，同一商品的事件进入同一分区
+		RequiredAcks: kafka.RequireAll,
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var cleanedAt time.Time
+		for {
+			s.publishProductEvents(context.Background(), writer)
+			if time.Since(cleanedAt) >= time.Hour {
+				cleanedAt = time.Now()
+				if err := s.db.Where("published_at < ?", cleanedAt.Add(-productEventRetention)).
+					Delete(&model.ProductEvent{}).Error; err != nil {
+					log.Printf("Failed to clean up product events: %v", err)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// publishProductEvents 按商品和序号顺序发送所有未发送的事件
+func (s *ProductService) publishProductEvents(ctx context.Context, writer *kafka.Writer) {
+	ok, err := RedisClient.SetNX(ctx, productEventLockKey, "locked", productEventLockTTL).Result()
+	if err != nil || !ok {
+		return
+	}
+	defer RedisClient.Del(ctx, productEventLockKey)
+	for {
+		var events []model.ProductEvent
+		if err := s.db.Where("published_at IS NULL").Order("product_id asc, seq asc").Limit(productEventBatchSize).Find(&events).Error; err != nil {
+			log.Printf("Failed to query product events: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+		messages := make([]kafka.Message, 0, len(events))
+		ids := make([]uint, 0, len(events))
+		for i := range events {
+			value, err := json.Marshal(newProductEventMessage(&events[i]))
+			if err != nil {
+				log.Printf("Failed to encode product event %d: %v", events[i].ID, err)
+				return
+			}
+			messages = append(messages, kafka.Message{
+				Key:   []byte(strconv.FormatUint(uint64(events[i].ProductID), 10)),
+				Value: value,
+			})
+			ids = append(ids, events[i].ID)
+		}
+		writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := writer.WriteMessages(writeCtx, messages...)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to send product events: %v", err)
+			return
+		}
+		if err := s.db.Model(&model.ProductEvent{}).Where("id IN ?", ids).Update("published_at", time.Now()).Error; err != nil {
+			log.Printf("Failed to mark product events as published: %v", err)
+			return
+		}
+		if len(events) < productEventBatchSize {
+			return
+		}
+	}
+}
+
+func newProductEventMessage(e *model.ProductEvent) *productEventMessage {
+	msg := &productEventMessage{
+		ID:         e.ID,
+		Event:      e.Event,
+		ProductID:  e.ProductID,
+		Seq:        e.Seq,
+		OperatorID: e.OperatorID,
+		OccurredAt: e.CreatedAt.Format(time.RFC3339),
+	}
+	if e.Before != "" {
+		msg.Before = json.RawMessage(e.Before)
+	}
+	if e.After != "" {
+		msg.After = json.RawMessage(e.After)
+	}
+	if e.Movement != "" {
+		msg.Movement = json.RawMessage(e.Movement)
+	}
+	return msg
+}
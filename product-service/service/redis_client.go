@@ -1,6 +1,7 @@
 package service
 
 import (
+	"common/cache"
 	"context"
 	"log"
 
@@ -23,4 +24,8 @@ func InitRedis(addr string) {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	log.Println("Successfully connected to Redis")
+
+	detailCache = cache.New(RedisClient, cache.Options{Name: "product:detail", TTL: productDetailTTL, NotFoundTTL: notFoundTTL, Jitter: cacheTTLJitter})
+	initListCaches(defaultListCacheTTLs)
+	productFilter = cache.NewBloomFilter(RedisClient, productFilterKey, productFilterCapacity, 0.01)
 }
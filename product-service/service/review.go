@@ -0,0 +1,238 @@
+package service
+
+import (
+	"common/middleware"
+	pbOrder "common/proto/gen/order"
+	pb "common/proto/gen/product"
+	"context"
+	"product-service/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxReviewLength       = 2000
+	maxReviewPageSize     = 50
+	defaultReviewPageSize = 10
+)
+
+func (s *ProductService) CreateReview(ctx context.Context, req *pb.CreateReviewRequest) (*pb.CreateReviewResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, status.Error(codes.InvalidArgument, "rating must be between 1 and 5")
+	}
+	content := strings.TrimSpace(req.Content)
+	if utf8.RuneCountInString(content) > maxReviewLength {
+		return nil, status.Errorf(codes.InvalidArgument, "review content must be at most %d characters", maxReviewLength)
+	}
+
+	// 只能评价自己已完成订单中的商品
+	resp, err := s.orderClient.GetOrder(middleware.ForwardAuth(ctx), &pbOrder.GetOrderRequest{OrderId: req.OrderId})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, status.Error(codes.NotFound, "order not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to query order")
+	}
+	order := resp.Order
+	if order.UserId != claims.UserID {
+		return nil, status.Error(codes.PermissionDenied, "order does not belong to current user")
+	}
+	if order.Status != pbOrder.OrderStatus_COMPLETED {
+		return nil, status.Error(codes.FailedPrecondition, "only completed orders can be reviewed")
+	}
+	var item *pbOrder.OrderItem
+	for _, it := range order.Items {
+		if it.Id == req.OrderItemId {
+			item = it
+			break
+		}
+	}
+	if item == nil || item.ProductId != req.ProductId {
+		return nil, status.Error(codes.InvalidArgument, "order item does not match product")
+	}
+
+	review := model.Review{
+		ProductID:   uint(req.ProductId),
+		UserID:      claims.UserID,
+		Username:    claims.Username,
+		OrderID:     req.OrderId,
+		OrderItemID: req.OrderItemId,
+		SkuSpec:     item.SkuSpec,
+		Rating:      int(req.Rating),
+		Content:     content,
+		Status:      int(pb.ReviewStatus_REVIEW_PENDING),
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&review)
+	if result.Error != nil {
+		return nil, status.Error(codes.Internal, "failed to create review")
+	}
+	if result.RowsAffected == 0 {
+		return nil, status.Error(codes.AlreadyExists, "order item has already been reviewed")
+	}
+	return &pb.CreateReviewResponse{
+		ReviewId: int64(review.ID),
+		Message:  "review submitted and waiting for moderation",
+	}, nil
+}
+
+func (s *ProductService) ListReviews(ctx context.Context, req *pb.ListReviewsRequest) (*pb.ListReviewsResponse, error) {
+	// 未通过审核的评价只有客服和管理员可以查看
+	statuses := []int{int(pb.ReviewStatus_REVIEW_APPROVED)}
+	if len(req.Statuses) > 0 {
+		moderator := middleware.HasRole(ctx, middleware.RoleAdmin, middleware.RoleSupport)
+		statuses = statuses[:0]
+		for _, st := range req.Statuses {
+			if st != pb.ReviewStatus_REVIEW_APPROVED && !moderator {
+				return nil, status.Error(codes.PermissionDenied, "permission denied")
+			}
+			statuses = append(statuses, int(st))
+		}
+	}
+	if req.ProductId <= 0 && !middleware.HasRole(ctx, middleware.RoleAdmin, middleware.RoleSupport) {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	page := int(req.Page)
+	pageSize := int(req.PageSize)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultReviewPageSize
+	}
+	if pageSize > maxReviewPageSize {
+		pageSize = maxReviewPageSize
+	}
+
+	q := s.db.Model(&model.Review{}).Where("status IN ?", statuses)
+	if req.ProductId > 0 {
+		q = q.Where("product_id = ?", req.ProductId)
+	}
+	if req.Rating > 0 {
+		q = q.Where("rating = ?", req.Rating)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to count reviews")
+	}
+	order := "id desc"
+	if req.SortBy == pb.ReviewSortBy_REVIEW_SORT_HELPFUL {
+		order = "helpful_count desc, id desc"
+	}
+	var reviews []model.Review
+	if err := q.Order(order).Offset((page - 1) * pageSize).Limit(pageSize).Find(&reviews).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to list reviews")
+	}
+	resp := &pb.ListReviewsResponse{Total: int32(total)}
+	for i := range reviews {
+		resp.Reviews = append(resp.Reviews, convertReviewModelToPB(&reviews[i]))
+	}
+	return resp, nil
+}
+
+func (s *ProductService) ModerateReview(ctx context.Context, req *pb.ModerateReviewRequest) (*pb.ModerateReviewResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin, middleware.RoleSupport); err != nil {
+		return nil, err
+	}
+	if req.Status != pb.ReviewStatus_REVIEW_APPROVED && req.Status != pb.ReviewStatus_REVIEW_REJECTED {
+		return nil, status.Error(codes.InvalidArgument, "status must be approved or rejected")
+	}
+	var review model.Review
+	if err := s.db.First(&review, req.ReviewId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.ModerateReviewResponse{Success: false, Message: "review not found"}, nil
+		}
+		return &pb.ModerateReviewResponse{Success: false, Message: "failed to query review"}, nil
+	}
+	review.Status = int(req.Status)
+	review.ModerationNote = req.Note
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, review.ProductID)
+	})
+	if err != nil {
+		return &pb.ModerateReviewResponse{Success: false, Message: "failed to moderate review"}, nil
+	}
+	invalidateProduct(ctx, []int64{int64(review.ProductID)}, sortListTag(pb.ProductSortBy_PRODUCT_SORT_RATING))
+	return &pb.ModerateReviewResponse{Success: true, Message: "review moderated"}, nil
+}
+
+func (s *ProductService) VoteReviewHelpful(ctx context.Context, req *pb.VoteReviewHelpfulRequest) (*pb.VoteReviewHelpfulResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	var review model.Review
+	if err := s.db.Where("status = ?", int(pb.ReviewStatus_REVIEW_APPROVED)).First(&review, req.ReviewId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.VoteReviewHelpfulResponse{Success: false, Message: "review not found"}, nil
+		}
+		return &pb.VoteReviewHelpfulResponse{Success: false, Message: "failed to query review"}, nil
+	}
+	if review.UserID == claims.UserID {
+		return &pb.VoteReviewHelpfulResponse{Success: false, Message: "cannot vote on your own review", HelpfulCount: int32(review.HelpfulCount)}, nil
+	}
+	voted := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ReviewVote{ReviewID: review.ID, UserID: claims.UserID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		voted = true
+		return tx.Model(&review).UpdateColumn("helpful_count", gorm.Expr("helpful_count + 1")).Error
+	})
+	if err != nil {
+		return &pb.VoteReviewHelpfulResponse{Success: false, Message: "failed to vote"}, nil
+	}
+	if !voted {
+		return &pb.VoteReviewHelpfulResponse{Success: false, Message: "already voted", HelpfulCount: int32(review.HelpfulCount)}, nil
+	}
+	return &pb.VoteReviewHelpfulResponse{Success: true, Message: "vote recorded", HelpfulCount: int32(review.HelpfulCount + 1)}, nil
+}
+
+// refreshProductRating 根据已通过审核的评价重新计算商品的平均评分和评分人数
+func refreshProductRating(tx *gorm.DB, productID uint) error {
+	var agg struct {
+		Avg   float64
+		Count int
+	}
+	if err := tx.Model(&model.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS avg, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, int(pb.ReviewStatus_REVIEW_APPROVED)).
+		Scan(&agg).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+		"rating_avg":   agg.Avg,
+		"rating_count": agg.Count,
+	}).Error
+}
+
+func convertReviewModelToPB(review *model.Review) *pb.Review {
+	return &pb.Review{
+		Id:             int64(review.ID),
+		ProductId:      int64(review.ProductID),
+		UserId:         review.UserID,
+		Username:       review.Username,
+		OrderId:        review.OrderID,
+		OrderItemId:    review.OrderItemID,
+		SkuSpec:        review.SkuSpec,
+		Rating:         int32(review.Rating),
+		Content:        review.Content,
+		Status:         pb.ReviewStatus(review.Status),
+		HelpfulCount:   int32(review.HelpfulCount),
+		ModerationNote: review.ModerationNote,
+		CreatedAt:      review.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	pb "common/proto/gen/product"
+	"context"
+	"log"
+	"product-service/model"
+	"product-service/search"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const maxSearchPageSize = 100
+
+// SearchProducts 全文搜索商品
+func (s *ProductService) SearchProducts(ctx context.Context, req *pb.SearchProductsRequest) (*pb.SearchProductsResponse, error) {
+	page := int(req.Page)
+	pageSize := int(req.PageSize)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if pageSize > maxSearchPageSize {
+		pageSize = maxSearchPageSize
+	}
+	q := search.Query{
+		Keyword:  strings.TrimSpace(req.Keyword),
+		MinPrice: req.MinPrice,
+		MaxPrice: req.MaxPrice,
+		From:     (page - 1) * pageSize,
+		Size:     pageSize,
+	}
+	if req.CategoryId > 0 {
+		categoryIDs, err := s.categoryDescendants(req.CategoryId)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to query categories")
+		}
+		q.CategoryIDs = categoryIDs
+	}
+	result, err := s.index.Search(q)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to search products")
+	}
+
+	// 按命中顺序回表查询商品详情
+	ids := make([]int64, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	var products []model.Product
+	if len(ids) > 0 {
+		if err := preloadProductDetails(s.db).Where("id IN ?", ids).Find(&products).Error; err != nil {
+			return nil, status.Error(codes.Internal, "failed to query products")
+		}
+	}
+	productMap := make(map[int64]*model.Product, len(products))
+	for i := range products {
+		productMap[int64(products[i].ID)] = &products[i]
+	}
+
+	resp := &pb.SearchProductsResponse{Total: int32(result.Total)}
+	for _, hit := range result.Hits {
+		if p, ok := productMap[hit.ID]; ok {
+			resp.Hits = append(resp.Hits, &pb.SearchHit{Product: convertProductModelToPB(p), Score: hit.Score})
+		}
+	}
+	for _, f := range result.PriceFacets {
+		resp.PriceFacets = append(resp.PriceFacets, &pb.PriceFacet{Min: f.Min, Max: f.Max, Count: int32(f.Count)})
+	}
+	for _, f := range result.CategoryFacets {
+		resp.CategoryFacets = append(resp.CategoryFacets, &pb.CategoryFacet{CategoryId: f.CategoryID, Count: int32(f.Count)})
+	}
+	return resp, nil
+}
+
+// indexProduct 将商品最新数据写入搜索索引，商品不存在或未上架时从索引中删除
+func (s *ProductService) indexProduct(productID int64) {
+	var product model.Product
+	err := s.db.Preload("Categories").First(&product, productID).Error
+	if err != nil || product.Status != int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE) {
+		s.removeFromIndex(productID)
+		return
+	}
+	if err := s.index.Put(productID, newSearchDocument(&product)); err != nil {
+		log.Printf("failed to index product %d: %v", productID, err)
+	}
+}
+
+// removeFromIndex 从搜索索引中删除商品
+func (s *ProductService) removeFromIndex(productID int64) {
+	if err := s.index.Delete(productID); err != nil {
+		log.Printf("failed to remove product %d from index: %v", productID, err)
+	}
+}
+
+// RebuildSearchIndex 从数据库全量重建搜索索引，只包含上架的商品
+func (s *ProductService) RebuildSearchIndex() error {
+	var products []model.Product
+	return s.db.Preload("Categories").Where("status = ?", int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE)).FindInBatches(&products, 500, func(tx *gorm.DB, batch int) error {
+		docs := make(map[int64]search.Document, len(products))
+		for i := range products {
+			docs[int64(products[i].ID)] = newSearchDocument(&products[i])
+		}
+		return s.index.PutBatch(docs)
+	}).Error
+}
+
+func newSearchDocument(product *model.Product) search.Document {
+	var categoryIDs []int64
+	for _, c := range product.Categories {
+		categoryIDs = append(categoryIDs, int64(c.ID))
+	}
+	return search.NewDocument(product.Name, product.Description, product.Price, categoryIDs)
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"fmt"
+	"product-service/model"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *ProductService) CreateSku(ctx context.Context, req *pb.CreateSkuRequest) (*pb.CreateSkuResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&model.Product{}, req.ProductId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "product not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to query product")
+	}
+	sku, err := newSkuModel(&pb.Sku{
+		SkuCode:    req.SkuCode,
+		Attributes: req.Attributes,
+		Price:      req.Price,
+		Stock:      req.Stock,
+		Image:      req.Image,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sku.ProductID = uint(req.ProductId)
+	// 包含已删除的规格，唯一索引对已删除的记录同样生效
+	var count int64
+	s.db.Unscoped().Model(&model.Sku{}).Where("sku_code = ?", sku.SkuCode).Count(&count)
+	if count > 0 {
+		return nil, status.Error(codes.AlreadyExists, "sku code already exists")
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, sku.ProductID, operatorID(ctx), func() error {
+			if err := tx.Create(&sku).Error; err != nil {
+				return err
+			}
+			if err := initPrice(tx, sku.ProductID, sku.ID, sku.Price, operatorID(ctx)); err != nil {
+				return err
+			}
+			return initStock(tx, sku.ProductID, sku.ID, sku.Stock, operatorID(ctx))
+		})
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Internal, "failed to create sku")
+	}
+	invalidateProduct(ctx, []int64{req.ProductId})
+	return &pb.CreateSkuResponse{
+		SkuId:   int64(sku.ID),
+		Message: "sku created successfully",
+	}, nil
+}
+
+func (s *ProductService) UpdateSku(ctx context.Context, req *pb.UpdateSkuRequest) (*pb.UpdateSkuResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var sku model.Sku
+	if err := s.db.First(&sku, req.SkuId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.UpdateSkuResponse{Success: false, Message: "sku not found"}, nil
+		}
+		return &pb.UpdateSkuResponse{Success: false, Message: "failed to query sku"}, nil
+	}
+	if req.SkuCode != "" && req.SkuCode != sku.SkuCode {
+		var count int64
+		s.db.Unscoped().Model(&model.Sku{}).Where("sku_code = ? AND id <> ?", req.SkuCode, sku.ID).Count(&count)
+		if count > 0 {
+			return &pb.UpdateSkuResponse{Success: false, Message: "sku code already exists"}, nil
+		}
+		sku.SkuCode = req.SkuCode
+	}
+	if len(req.Attributes) > 0 {
+		sku.SetAttributes(req.Attributes)
+	}
+	if req.Image != "" {
+		sku.Image = req.Image
+	}
+	if req.Stock != nil && *req.Stock < 0 {
+		return nil, status.Error(codes.InvalidArgument, "sku stock cannot be negative")
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, sku.ProductID, operatorID(ctx), func() error {
+			if err := tx.Omit("Stock", "Price").Save(&sku).Error; err != nil {
+				return err
+			}
+			if req.Price != 0 {
+				if err := changePrice(tx, &model.PriceChange{
+					ProductID:  sku.ProductID,
+					SkuID:      sku.ID,
+					NewPrice:   req.Price,
+					Reason:     int(pb.PriceChangeReason_PRICE_REASON_MANUAL),
+					OperatorID: operatorID(ctx),
+				}); err != nil {
+					return err
+				}
+			}
+			if req.Stock == nil {
+				return nil
+			}
+			// 兼容旧接口：库存不再直接覆盖，按与当前总库存的差额在默认仓库记录为手工调整，按仓库调整请使用 AdjustStock
+			var current model.Sku
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "stock").First(&current, sku.ID).Error; err != nil {
+				return err
+			}
+			if int(*req.Stock) != current.Stock {
+				return changeStock(tx, &model.StockMovement{
+					ProductID:  sku.ProductID,
+					SkuID:      sku.ID,
+					Delta:      int(*req.Stock) - current.Stock,
+					Reason:     int(pb.StockReason_STOCK_REASON_ADJUSTMENT),
+					Note:       "set by UpdateSku",
+					OperatorID: operatorID(ctx),
+				}, false)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return &pb.UpdateSkuResponse{Success: false, Message: "failed to update sku"}, nil
+	}
+	invalidateProduct(ctx, []int64{int64(sku.ProductID)})
+	return &pb.UpdateSkuResponse{Success: true, Message: "sku updated successfully"}, nil
+}
+
+func (s *ProductService) DeleteSku(ctx context.Context, req *pb.DeleteSkuRequest) (*pb.DeleteSkuResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var sku model.Sku
+	if err := s.db.First(&sku, req.SkuId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.DeleteSkuResponse{Success: false, Message: "sku not found"}, nil
+		}
+		return &pb.DeleteSkuResponse{Success: false, Message: "failed to query sku"}, nil
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, sku.ProductID, operatorID(ctx), func() error {
+			// 释放规格编码，之后可以重新创建同编码的规格
+			if err := tx.Model(&sku).Update("sku_code", deletedSkuCode(&sku)).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&sku).Error
+		})
+	})
+	if err != nil {
+		return &pb.DeleteSkuResponse{Success: false, Message: "failed to delete sku"}, nil
+	}
+	invalidateProduct(ctx, []int64{int64(sku.ProductID)})
+	return &pb.DeleteSkuResponse{Success: true, Message: "sku deleted successfully"}, nil
+}
+
+// deletedSkuCode 已删除规格的编码，以ID开头保证唯一，保留原编码便于追溯
+func deletedSkuCode(sku *model.Sku) string {
+	code := []rune(fmt.Sprintf("deleted:%d:%s", sku.ID, sku.SkuCode))
+	if len(code) > 64 {
+		code = code[:64]
+	}
+	return string(code)
+}
+
+// newSkuModel 校验并构造规格模型
+func newSkuModel(in *pb.Sku) (model.Sku, error) {
+	if in.SkuCode == "" {
+		return model.Sku{}, status.Error(codes.InvalidArgument, "sku code is required")
+	}
+	if in.Price <= 0 {
+		return model.Sku{}, status.Error(codes.InvalidArgument, "sku price must be positive")
+	}
+	if in.Stock < 0 {
+		return model.Sku{}, status.Error(codes.InvalidArgument, "sku stock cannot be negative")
+	}
+	sku := model.Sku{
+		SkuCode: in.SkuCode,
+		Price:   in.Price,
+		Stock:   int(in.Stock),
+		Image:   in.Image,
+	}
+	sku.SetAttributes(in.Attributes)
+	return sku, nil
+}
+
+func convertSkuModelToPB(sku *model.Sku) *pb.Sku {
+	return &pb.Sku{
+		Id:         int64(sku.ID),
+		ProductId:  int64(sku.ProductID),
+		SkuCode:    sku.SkuCode,
+		Attributes: sku.AttributeMap(),
+		Price:      sku.Price,
+		Stock:      int32(sku.Stock),
+		Image:      sku.Image,
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"fmt"
+	"product-service/model"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxStockHistoryPageSize     = 100
+	defaultStockHistoryPageSize = 20
+)
+
+// manualStockReasons AdjustStock 允许使用的原因，下单占用和取消释放由订单事件自动记录
+var manualStockReasons = map[pb.StockReason]bool{
+	pb.StockReason_STOCK_REASON_ADJUSTMENT: true,
+	pb.StockReason_STOCK_REASON_SALE:       true,
+	pb.StockReason_STOCK_REASON_RETURN:     true,
+}
+
+// AdjustStock 按变化量调整库存并记录流水
+func (s *ProductService) AdjustStock(ctx context.Context, req *pb.AdjustStockRequest) (*pb.AdjustStockResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Delta == 0 {
+		return nil, status.Error(codes.InvalidArgument, "delta must not be zero")
+	}
+	if !manualStockReasons[req.Reason] {
+		return nil, status.Error(codes.InvalidArgument, "reason must be adjustment, sale or return")
+	}
+	if req.SkuId == 0 {
+		// 多规格商品的库存在规格上
+		var count int64
+		if err := s.db.Model(&model.Sku{}).Where("product_id = ?", req.ProductId).Count(&count).Error; err != nil {
+			return nil, status.Error(codes.Internal, "failed to query skus")
+		}
+		if count > 0 {
+			return nil, status.Error(codes.InvalidArgument, "sku_id is required for products with skus")
+		}
+	}
+	if req.WarehouseId > 0 {
+		if err := s.db.First(&model.Warehouse{}, req.WarehouseId).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, status.Error(codes.NotFound, "warehouse not found")
+			}
+			return nil, status.Error(codes.Internal, "failed to query warehouse")
+		}
+	}
+	movement := model.StockMovement{
+		ProductID:   uint(req.ProductId),
+		SkuID:       uint(req.SkuId),
+		WarehouseID: uint(req.WarehouseId),
+		Delta:       int(req.Delta),
+		Reason:      int(req.Reason),
+		Reference:   strings.TrimSpace(req.Reference),
+		Note:        strings.TrimSpace(req.Note),
+		OperatorID:  operatorID(ctx),
+	}
+	var total int
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := changeStock(tx, &movement, false); err != nil {
+			return err
+		}
+		var err error
+		total, err = totalStock(tx, movement.ProductID, movement.SkuID)
+		return err
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Internal, "failed to adjust stock")
+	}
+	invalidateProduct(ctx, []int64{req.ProductId})
+	return &pb.AdjustStockResponse{
+		MovementId: int64(movement.ID),
+		Stock:      int32(movement.StockAfter),
+		TotalStock: int32(total),
+	}, nil
+}
+
+// SetLowStockThreshold 设置低库存预警阈值，只在之后的库存变化越过阈值时预警
+func (s *ProductService) SetLowStockThreshold(ctx context.Context, req *pb.SetLowStockThresholdRequest) (*pb.SetLowStockThresholdResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.Threshold < 0 {
+		return nil, status.Error(codes.InvalidArgument, "threshold must not be negative")
+	}
+	var product model.Product
+	if err := s.db.Select("id", "low_stock_threshold").First(&product, req.ProductId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.SetLowStockThresholdResponse{Success: false, Message: "product not found"}, nil
+		}
+		return &pb.SetLowStockThresholdResponse{Success: false, Message: "failed to query product"}, nil
+	}
+	if product.LowStockThreshold == int(req.Threshold) {
+		return &pb.SetLowStockThresholdResponse{Success: true, Message: "low stock threshold unchanged"}, nil
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return trackProduct(tx, product.ID, operatorID(ctx), func() error {
+			return tx.Model(&product).Update("low_stock_threshold", int(req.Threshold)).Error
+		})
+	})
+	if err != nil {
+		return &pb.SetLowStockThresholdResponse{Success: false, Message: "failed to update low stock threshold"}, nil
+	}
+	invalidateProduct(ctx, []int64{req.ProductId})
+	return &pb.SetLowStockThresholdResponse{Success: true, Message: "low stock threshold updated"}, nil
+}
+
+// GetStockHistory 分页查询库存流水，最新的在前
+func (s *ProductService) GetStockHistory(ctx context.Context, req *pb.GetStockHistoryRequest) (*pb.GetStockHistoryResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if req.ProductId <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	page := int(req.Page)
+	pageSize := int(req.PageSize)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultStockHistoryPageSize
+	}
+	if pageSize > maxStockHistoryPageSize {
+		pageSize = maxStockHistoryPageSize
+	}
+
+	q := s.db.Model(&model.StockMovement{}).Where("product_id = ?", req.ProductId)
+	if req.SkuId > 0 {
+		q = q.Where("sku_id = ?", req.SkuId)
+	}
+	if req.WarehouseId > 0 {
+		q = q.Where("warehouse_id = ?", req.WarehouseId)
+	}
+	if len(req.Reasons) > 0 {
+		reasons := make([]int, 0, len(req.Reasons))
+		for _, r := range req.Reasons {
+			reasons = append(reasons, int(r))
+		}
+		q = q.Where("reason IN ?", reasons)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to count stock movements")
+	}
+	var movements []model.StockMovement
+	if err := q.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&movements).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query stock movements")
+	}
+	resp := &pb.GetStockHistoryResponse{Total: int32(total)}
+	for i := range movements {
+		resp.Movements = append(resp.Movements, convertStockMovementToPB(&movements[i]))
+	}
+	return resp, nil
+}
+
+// changeStock 在事务中按 m.Delta 修改某个仓库中商品或规格的库存，同步商品或规格的总库存，并写入流水。
+// m.WarehouseID 为 0 时使用默认仓库。修改前锁定仓库库存行，保证并发修改时流水前后衔接；
+// allowNegative 为 false 时仓库库存不足返回 FailedPrecondition。同时记录库存变化事件，越过预警阈值时记录预警事件
+func changeStock(tx *gorm.DB, m *model.StockMovement, allowNegative bool) error {
+	if m.WarehouseID == 0 {
+		warehouse, err := defaultWarehouse(tx)
+		if err != nil {
+			return err
+		}
+		m.WarehouseID = warehouse.ID
+	}
+	// 总库存记录在规格上，无规格商品记录在商品上
+	var total *gorm.DB
+	if m.SkuID > 0 {
+		total = tx.Model(&model.Sku{}).Where("id = ? AND product_id = ?", m.SkuID, m.ProductID)
+	} else {
+		total = tx.Model(&model.Product{}).Where("id = ?", m.ProductID)
+	}
+	result := total.Update("stock", gorm.Expr("stock + ?", m.Delta))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if m.SkuID > 0 {
+			return status.Error(codes.NotFound, "sku not found")
+		}
+		return status.Error(codes.NotFound, "product not found")
+	}
+
+	// 仓库中还没有该商品时先插入库存为 0 的记录
+	row := model.WarehouseStock{WarehouseID: m.WarehouseID, ProductID: m.ProductID, SkuID: m.SkuID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("warehouse_id = ? AND product_id = ? AND sku_id = ?", m.WarehouseID, m.ProductID, m.SkuID).
+		First(&row).Error; err != nil {
+		return err
+	}
+	warehouseBefore := row.Stock
+	stock := row.Stock + m.Delta
+	if stock < 0 && !allowNegative {
+		return status.Errorf(codes.FailedPrecondition, "stock not enough, current stock in warehouse %d is %d", m.WarehouseID, row.Stock)
+	}
+	if err := tx.Model(&row).Update("stock", stock).Error; err != nil {
+		return err
+	}
+	m.StockAfter = stock
+	if err := tx.Create(m).Error; err != nil {
+		return err
+	}
+	return recordStockEvent(tx, m, warehouseBefore)
+}
+
+// initStock 记录新建商品或规格的初始库存：放入默认仓库并写入流水，总库存已在创建时写入
+func initStock(tx *gorm.DB, productID, skuID uint, stock int, operatorID int64) error {
+	if stock == 0 {
+		return nil
+	}
+	warehouse, err := defaultWarehouse(tx)
+	if err != nil {
+		return err
+	}
+	row := model.WarehouseStock{WarehouseID: warehouse.ID, ProductID: productID, SkuID: skuID, Stock: stock}
+	if err := tx.Create(&row).Error; err != nil {
+		return err
+	}
+	return tx.Create(&model.StockMovement{
+		ProductID:   productID,
+		SkuID:       skuID,
+		WarehouseID: warehouse.ID,
+		Delta:       stock,
+		StockAfter:  stock,
+		Reason:      int(pb.StockReason_STOCK_REASON_INITIAL),
+		OperatorID:  operatorID,
+	}).Error
+}
+
+// totalStock 商品或规格在所有仓库的库存之和
+func totalStock(tx *gorm.DB, productID, skuID uint) (int, error) {
+	if skuID > 0 {
+		var sku model.Sku
+		err := tx.Select("id", "stock").First(&sku, skuID).Error
+		return sku.Stock, err
+	}
+	var product model.Product
+	err := tx.Select("id", "stock").First(&product, productID).Error
+	return product.Stock, err
+}
+
+// orderReference 订单在库存流水中的关联单据
+func orderReference(orderID int64) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// operatorID 当前操作人，未登录时为 0
+func operatorID(ctx context.Context) int64 {
+	if claims, ok := middleware.ClaimsFromContext(ctx); ok {
+		return claims.UserID
+	}
+	return 0
+}
+
+func convertStockMovementToPB(m *model.StockMovement) *pb.StockMovement {
+	return &pb.StockMovement{
+		Id:          int64(m.ID),
+		ProductId:   int64(m.ProductID),
+		SkuId:       int64(m.SkuID),
+		Delta:       int32(m.Delta),
+		StockAfter:  int32(m.StockAfter),
+		Reason:      pb.StockReason(m.Reason),
+		Reference:   m.Reference,
+		Note:        m.Note,
+		OperatorId:  m.OperatorID,
+		CreatedAt:   m.CreatedAt.Format(time.RFC3339),
+		WarehouseId: int64(m.WarehouseID),
+	}
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/product"
+	"context"
+	"math"
+	"product-service/model"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *ProductService) CreateWarehouse(ctx context.Context, req *pb.CreateWarehouseRequest) (*pb.CreateWarehouseResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	code := strings.TrimSpace(req.Code)
+	name := strings.TrimSpace(req.Name)
+	if code == "" || name == "" {
+		return nil, status.Error(codes.InvalidArgument, "warehouse code and name are required")
+	}
+	var count int64
+	s.db.Model(&model.Warehouse{}).Where("code = ?", code).Count(&count)
+	if count > 0 {
+		return nil, status.Error(codes.AlreadyExists, "warehouse code already exists")
+	}
+	warehouse := model.Warehouse{
+		Code:      code,
+		Name:      name,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Priority:  int(req.Priority),
+		Active:    true,
+	}
+	if err := s.db.Create(&warehouse).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to create warehouse")
+	}
+	return &pb.CreateWarehouseResponse{
+		WarehouseId: int64(warehouse.ID),
+		Message:     "warehouse created successfully",
+	}, nil
+}
+
+// UpdateWarehouse 修改仓库信息，name 为空时保持不变，其余字段整体覆盖
+func (s *ProductService) UpdateWarehouse(ctx context.Context, req *pb.UpdateWarehouseRequest) (*pb.UpdateWarehouseResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var warehouse model.Warehouse
+	if err := s.db.First(&warehouse, req.WarehouseId).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &pb.UpdateWarehouseResponse{Success: false, Message: "warehouse not found"}, nil
+		}
+		return &pb.UpdateWarehouseResponse{Success: false, Message: "failed to query warehouse"}, nil
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		warehouse.Name = name
+	}
+	warehouse.Latitude = req.Latitude
+	warehouse.Longitude = req.Longitude
+	warehouse.Priority = int(req.Priority)
+	warehouse.Active = req.Active
+	if err := s.db.Save(&warehouse).Error; err != nil {
+		return &pb.UpdateWarehouseResponse{Success: false, Message: "failed to update warehouse"}, nil
+	}
+	return &pb.UpdateWarehouseResponse{Success: true, Message: "warehouse updated successfully"}, nil
+}
+
+func (s *ProductService) ListWarehouses(ctx context.Context, req *pb.ListWarehousesRequest) (*pb.ListWarehousesResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var warehouses []model.Warehouse
+	if err := s.db.Order("priority asc, id asc").Find(&warehouses).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to list warehouses")
+	}
+	resp := &pb.ListWarehousesResponse{}
+	for i := range warehouses {
+		resp.Warehouses = append(resp.Warehouses, convertWarehouseModelToPB(&warehouses[i]))
+	}
+	return resp, nil
+}
+
+// GetWarehouseStock 商品及其规格在各仓库的库存
+func (s *ProductService) GetWarehouseStock(ctx context.Context, req *pb.GetWarehouseStockRequest) (*pb.GetWarehouseStockResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	var rows []model.WarehouseStock
+	if err := s.db.Where("product_id = ?", req.ProductId).Order("sku_id asc, warehouse_id asc").Find(&rows).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query warehouse stock")
+	}
+	resp := &pb.GetWarehouseStockResponse{}
+	for _, row := range rows {
+		resp.Stocks = append(resp.Stocks, &pb.WarehouseStock{
+			WarehouseId: int64(row.WarehouseID),
+			ProductId:   int64(row.ProductID),
+			SkuId:       int64(row.SkuID),
+			Stock:       int32(row.Stock),
+		})
+	}
+	return resp, nil
+}
+
+// ReserveStock 按分配策略为每个订单项选择仓库并占用库存，任一订单项库存不足时全部不占用。
+// 只允许内部服务和管理员调用
+func (s *ProductService) ReserveStock(ctx context.Context, req *pb.ReserveStockRequest) (*pb.ReserveStockResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleService, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	reference := strings.TrimSpace(req.Reference)
+	if reference == "" {
+		return nil, status.Error(codes.InvalidArgument, "reference is required")
+	}
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+		}
+	}
+	var allocations []*pb.StockAllocation
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 同一单据重复调用时返回已有的分配结果
+		var reserved []model.StockMovement
+		if err := tx.Where("reference = ? AND reason = ?", reference, int(pb.StockReason_STOCK_REASON_RESERVATION)).
+			Order("id").Find(&reserved).Error; err != nil {
+			return err
+		}
+		if len(reserved) > 0 {
+			allocations = allocationsFromMovements(req.Items, reserved)
+			return nil
+		}
+
+		var warehouses []model.Warehouse
+		if err := tx.Where("active = ?", true).Order("priority asc, id asc").Find(&warehouses).Error; err != nil {
+			return err
+		}
+		for i, item := range req.Items {
+			parts, err := allocateStock(tx, warehouses, item, req)
+			if err != nil {
+				return err
+			}
+			for _, part := range parts {
+				err := changeStock(tx, &model.StockMovement{
+					ProductID:   uint(item.ProductId),
+					SkuID:       uint(item.SkuId),
+					WarehouseID: part.WarehouseID,
+					Delta:       -part.Quantity,
+					Reason:      int(pb.StockReason_STOCK_REASON_RESERVATION),
+					Reference:   reference,
+					OperatorID:  operatorID(ctx),
+				}, false)
+				if err != nil {
+					return err
+				}
+				allocations = append(allocations, &pb.StockAllocation{
+					ItemIndex:   int32(i),
+					WarehouseId: int64(part.WarehouseID),
+					Quantity:    int32(part.Quantity),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Internal, "failed to reserve stock")
+	}
+	productIDs := make([]int64, 0, len(req.Items))
+	for _, item := range req.Items {
+		productIDs = append(productIDs, item.ProductId)
+	}
+	invalidateProduct(ctx, productIDs)
+	return &pb.ReserveStockResponse{Allocations: allocations}, nil
+}
+
+// ReleaseStock 释放单据占用的库存，重复调用只释放一次。只允许内部服务和管理员调用
+func (s *ProductService) ReleaseStock(ctx context.Context, req *pb.ReleaseStockRequest) (*pb.ReleaseStockResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleService, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	reference := strings.TrimSpace(req.Reference)
+	if reference == "" {
+		return nil, status.Error(codes.InvalidArgument, "reference is required")
+	}
+	var productIDs []int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		productIDs, err = releaseStock(tx, reference)
+		return err
+	})
+	if err != nil {
+		return &pb.ReleaseStockResponse{Success: false, Message: "failed to release stock"}, nil
+	}
+	invalidateProduct(ctx, productIDs)
+	return &pb.ReleaseStockResponse{Success: true, Message: "stock released"}, nil
+}
+
+// releaseStock 按单据的占用流水逐条退回原仓库，返回涉及的商品。
+// 已释放过或没有占用记录的单据（如上线前创建的订单）不做处理
+func releaseStock(tx *gorm.DB, reference string) ([]int64, error) {
+	var released int64
+	if err := tx.Model(&model.StockMovement{}).
+		Where("reference = ? AND reason = ?", reference, int(pb.StockReason_STOCK_REASON_CANCELLATION)).
+		Count(&released).Error; err != nil {
+		return nil, err
+	}
+	if released > 0 {
+		return nil, nil
+	}
+	var reservations []model.StockMovement
+	if err := tx.Where("reference = ? AND reason = ?", reference, int(pb.StockReason_STOCK_REASON_RESERVATION)).
+		Order("id").Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	var productIDs []int64
+	for _, r := range reservations {
+		err := changeStock(tx, &model.StockMovement{
+			ProductID:   r.ProductID,
+			SkuID:       r.SkuID,
+			WarehouseID: r.WarehouseID,
+			Delta:       -r.Delta,
+			Reason:      int(pb.StockReason_STOCK_REASON_CANCELLATION),
+			Reference:   r.Reference,
+		}, true)
+		if status.Code(err) == codes.NotFound {
+			// 商品或规格已被删除，无需退回
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		productIDs = append(productIDs, int64(r.ProductID))
+	}
+	return productIDs, nil
+}
+
+// warehouseAllocation 订单项在某个仓库占用的数量
+type warehouseAllocation struct {
+	WarehouseID uint
+	Quantity    int
+}
+
+// allocateStock 按策略为订单项选择仓库。先锁定总库存所在的行，与 changeStock 的加锁顺序一致
+func allocateStock(tx *gorm.DB, warehouses []model.Warehouse, item *pb.ReserveStockItem, req *pb.ReserveStockRequest) ([]warehouseAllocation, error) {
+	locked := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	// 无规格商品的库存在商品行上，直接锁定商品行
+	productQuery := tx
+	if item.SkuId == 0 {
+		productQuery = locked
+	}
+	var product model.Product
+	err := productQuery.Select("id", "status").First(&product, item.ProductId).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, status.Errorf(codes.NotFound, "product %d not found", item.ProductId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if product.Status != int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE) {
+		return nil, status.Errorf(codes.FailedPrecondition, "product %d is not available", item.ProductId)
+	}
+	if item.SkuId > 0 {
+		err = locked.Select("id").Where("product_id = ?", item.ProductId).First(&model.Sku{}, item.SkuId).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Errorf(codes.NotFound, "product %d not found", item.ProductId)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []model.WarehouseStock
+	if err := tx.Where("product_id = ? AND sku_id = ? AND stock > 0", item.ProductId, item.SkuId).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	stocks := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		stocks[row.WarehouseID] = row.Stock
+	}
+
+	candidates := warehouses
+	hasLocation := req.Latitude != 0 || req.Longitude != 0
+	if hasLocation && req.Strategy != pb.AllocationStrategy_ALLOCATION_PRIORITY {
+		candidates = sortByDistance(warehouses, req.Latitude, req.Longitude)
+	}
+	need := int(item.Quantity)
+	if req.Strategy == pb.AllocationStrategy_ALLOCATION_SPLIT {
+		var parts []warehouseAllocation
+		for _, w := range candidates {
+			take := min(stocks[w.ID], need)
+			if take <= 0 {
+				continue
+			}
+			parts = append(parts, warehouseAllocation{WarehouseID: w.ID, Quantity: take})
+			if need -= take; need == 0 {
+				return parts, nil
+			}
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "product %d stock not enough", item.ProductId)
+	}
+	for _, w := range candidates {
+		if stocks[w.ID] >= need {
+			return []warehouseAllocation{{WarehouseID: w.ID, Quantity: need}}, nil
+		}
+	}
+	return nil, status.Errorf(codes.FailedPrecondition, "product %d stock not enough in any single warehouse", item.ProductId)
+}
+
+// sortByDistance 按与收货地的距离排序，距离相同时保持优先级顺序
+func sortByDistance(warehouses []model.Warehouse, lat, lng float64) []model.Warehouse {
+	sorted := make([]model.Warehouse, len(warehouses))
+	copy(sorted, warehouses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return distanceKm(lat, lng, sorted[i].Latitude, sorted[i].Longitude) <
+			distanceKm(lat, lng, sorted[j].Latitude, sorted[j].Longitude)
+	})
+	return sorted
+}
+
+// distanceKm 两个经纬度之间的球面距离
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// allocationsFromMovements 由已有的占用流水还原分配结果，按请求顺序将流水对应到订单项
+func allocationsFromMovements(items []*pb.ReserveStockItem, movements []model.StockMovement) []*pb.StockAllocation {
+	used := make([]bool, len(movements))
+	var allocations []*pb.StockAllocation
+	for i, item := range items {
+		need := int(item.Quantity)
+		for j, m := range movements {
+			if need <= 0 {
+				break
+			}
+			if used[j] || int64(m.ProductID) != item.ProductId || int64(m.SkuID) != item.SkuId {
+				continue
+			}
+			used[j] = true
+			need += m.Delta
+			allocations = append(allocations, &pb.StockAllocation{
+				ItemIndex:   int32(i),
+				WarehouseId: int64(m.WarehouseID),
+				Quantity:    int32(-m.Delta),
+			})
+		}
+	}
+	return allocations
+}
+
+// defaultWarehouse 优先级最高的启用仓库
+func defaultWarehouse(tx *gorm.DB) (*model.Warehouse, error) {
+	var warehouse model.Warehouse
+	if err := tx.Where("active = ?", true).Order("priority asc, id asc").First(&warehouse).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.FailedPrecondition, "no active warehouse")
+		}
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+// MigrateWarehouseStock 首次启用多仓库时创建默认仓库，并将现有库存全部放入默认仓库
+func MigrateWarehouseStock(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&model.Warehouse{}).Unscoped().Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		warehouse := model.Warehouse{Code: "DEFAULT", Name: "默认仓库", Active: true}
+		if err := tx.Create(&warehouse).Error; err != nil {
+			return err
+		}
+		// 多规格商品的库存在规格上
+		if err := tx.Exec(`INSERT INTO warehouse_stocks (warehouse_id, product_id, sku_id, stock)
+			SELECT ?, id, 0, stock FROM products
+			WHERE deleted_at IS NULL AND stock <> 0
+			AND id NOT IN (SELECT product_id FROM skus WHERE deleted_at IS NULL)`, warehouse.ID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO warehouse_stocks (warehouse_id, product_id, sku_id, stock)
+			SELECT ?, product_id, id, stock FROM skus
+			WHERE deleted_at IS NULL AND stock <> 0`, warehouse.ID).Error
+	})
+}
+
+func convertWarehouseModelToPB(w *model.Warehouse) *pb.Warehouse {
+	return &pb.Warehouse{
+		Id:        int64(w.ID),
+		Code:      w.Code,
+		Name:      w.Name,
+		Latitude:  w.Latitude,
+		Longitude: w.Longitude,
+		Priority:  int32(w.Priority),
+		Active:    w.Active,
+	}
+}
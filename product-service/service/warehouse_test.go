@@ -0,0 +1,165 @@
+package service
+
+import (
+	pb "common/proto/gen/product"
+	"product-service/model"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 内存中的 SQLite 数据库，每个测试独立
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库只存在于单个连接中
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.Product{}, &model.Sku{}, &model.Warehouse{}, &model.WarehouseStock{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAllocateStock(t *testing.T) {
+	db := newTestDB(t)
+	// 三个仓库按优先级排列，北京仓最优先，广州仓离深圳最近
+	warehouses := []model.Warehouse{
+		{Model: gorm.Model{ID: 1}, Code: "BJ", Name: "北京仓", Latitude: 39.9, Longitude: 116.4, Priority: 1, Active: true},
+		{Model: gorm.Model{ID: 2}, Code: "SH", Name: "上海仓", Latitude: 31.2, Longitude: 121.5, Priority: 2, Active: true},
+		{Model: gorm.Model{ID: 3}, Code: "GZ", Name: "广州仓", Latitude: 23.1, Longitude: 113.3, Priority: 3, Active: true},
+	}
+	products := []model.Product{
+		{Model: gorm.Model{ID: 1}, Name: "无规格商品", Status: int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE)},
+		{Model: gorm.Model{ID: 2}, Name: "多规格商品", Status: int(pb.ProductStatus_PRODUCT_STATUS_ACTIVE)},
+		{Model: gorm.Model{ID: 3}, Name: "下架商品", Status: int(pb.ProductStatus_PRODUCT_STATUS_ARCHIVED)},
+	}
+	skus := []model.Sku{{Model: gorm.Model{ID: 10}, ProductID: 2, SkuCode: "RED"}}
+	stocks := []model.WarehouseStock{
+		{WarehouseID: 1, ProductID: 1, Stock: 2},
+		{WarehouseID: 2, ProductID: 1, Stock: 5},
+		{WarehouseID: 3, ProductID: 1, Stock: 4},
+		{WarehouseID: 2, ProductID: 2, SkuID: 10, Stock: 3},
+		{WarehouseID: 3, ProductID: 3, Stock: 10},
+	}
+	for _, rows := range []interface{}{&warehouses, &products, &skus, &stocks} {
+		if err := db.Create(rows).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	const shenzhenLat, shenzhenLng = 22.5, 114.1
+
+	tests := []struct {
+		name     string
+		item     *pb.ReserveStockItem
+		strategy pb.AllocationStrategy
+		lat, lng float64
+		want     []warehouseAllocation
+		wantCode codes.Code
+	}{
+		{
+			name:     "priority skips warehouses without enough stock",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 3},
+			strategy: pb.AllocationStrategy_ALLOCATION_PRIORITY,
+			want:     []warehouseAllocation{{WarehouseID: 2, Quantity: 3}},
+		},
+		{
+			name:     "priority ignores location",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 2},
+			strategy: pb.AllocationStrategy_ALLOCATION_PRIORITY,
+			lat:      shenzhenLat, lng: shenzhenLng,
+			want: []warehouseAllocation{{WarehouseID: 1, Quantity: 2}},
+		},
+		{
+			name:     "nearest picks closest warehouse with enough stock",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 4},
+			strategy: pb.AllocationStrategy_ALLOCATION_NEAREST,
+			lat:      shenzhenLat, lng: shenzhenLng,
+			want: []warehouseAllocation{{WarehouseID: 3, Quantity: 4}},
+		},
+		{
+			name:     "nearest without location falls back to priority",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 4},
+			strategy: pb.AllocationStrategy_ALLOCATION_NEAREST,
+			want:     []warehouseAllocation{{WarehouseID: 2, Quantity: 4}},
+		},
+		{
+			name:     "single warehouse cannot cover quantity",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 6},
+			strategy: pb.AllocationStrategy_ALLOCATION_PRIORITY,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "split by priority",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 6},
+			strategy: pb.AllocationStrategy_ALLOCATION_SPLIT,
+			want:     []warehouseAllocation{{WarehouseID: 1, Quantity: 2}, {WarehouseID: 2, Quantity: 4}},
+		},
+		{
+			name:     "split by distance",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 10},
+			strategy: pb.AllocationStrategy_ALLOCATION_SPLIT,
+			lat:      shenzhenLat, lng: shenzhenLng,
+			want: []warehouseAllocation{{WarehouseID: 3, Quantity: 4}, {WarehouseID: 2, Quantity: 5}, {WarehouseID: 1, Quantity: 1}},
+		},
+		{
+			name:     "split cannot cover total stock",
+			item:     &pb.ReserveStockItem{ProductId: 1, Quantity: 12},
+			strategy: pb.AllocationStrategy_ALLOCATION_SPLIT,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "sku stock",
+			item:     &pb.ReserveStockItem{ProductId: 2, SkuId: 10, Quantity: 3},
+			strategy: pb.AllocationStrategy_ALLOCATION_PRIORITY,
+			want:     []warehouseAllocation{{WarehouseID: 2, Quantity: 3}},
+		},
+		{
+			name:     "sku of another product",
+			item:     &pb.ReserveStockItem{ProductId: 1, SkuId: 10, Quantity: 1},
+			strategy: pb.AllocationStrategy_ALLOCATION_PRIORITY,
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "product not found",
+			item:     &pb.ReserveStockItem{ProductId: 99, Quantity: 1},
+			strategy: pb.AllocationStrategy_ALLOCATION_PRIORITY,
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "product not active",
+			item:     &pb.ReserveStockItem{ProductId: 3, Quantity: 1},
+			strategy: pb.AllocationStrategy_ALLOCATION_PRIORITY,
+			wantCode: codes.FailedPrecondition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.ReserveStockRequest{Strategy: tt.strategy, Latitude: tt.lat, Longitude: tt.lng}
+			got, err := allocateStock(db, warehouses, tt.item, req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("allocateStock error = %v, want %v", err, tt.wantCode)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("allocations = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("allocations = %+v, want %+v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
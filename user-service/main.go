@@ -6,9 +6,11 @@ import (
 	"log"
 	"net"
 	"os"
+	"strings"
 	"user-service/model"
 	"user-service/service"
 
+	pbProduct "common/proto/gen/product"
 	pb "common/proto/gen/user"
 
 	"github.com/joho/godotenv"
@@ -36,10 +38,24 @@ func main() {
 	}
 
 	// 自动迁移数据库表
-	if err := db.AutoMigrate(&model.User{}); err != nil {
+	// 订阅的唯一索引加入了规格，删除不含规格的旧索引
+	if db.Migrator().HasIndex(&model.ProductSubscription{}, "idx_subscription_user_product") {
+		if err := db.Migrator().DropIndex(&model.ProductSubscription{}, "idx_subscription_user_product"); err != nil {
+			log.Fatalf("failed to drop subscription index: %v", err)
+		}
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.StockAlert{}, &model.WishlistItem{}, &model.ProductSubscription{}, &model.Notification{}, &model.RefreshToken{}); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 
+	// 初始化 Redis，保存 token 吊销记录
+	redisAddr := fmt.Sprintf("%s:%s",
+		os.Getenv("REDIS_HOST"),
+		os.Getenv("REDIS_PORT"),
+	)
+	service.InitRedis(redisAddr)
+	middleware.SetTokenDenylist(service.RedisClient)
+
 	// 创建 gRPC 服务器
 	port := os.Getenv("GRPC_PORT")
 	lis, err := net.Listen("tcp", ":"+port)
@@ -47,13 +63,29 @@ func main() {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
+	// 连接商品服务，用于收藏夹和商品订阅查询商品信息
+	productAddr := os.Getenv("PRODUCT_SERVICE_ADDR")
+	productConn, err := grpc.Dial(productAddr, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("failed to connect to product service: %v", err)
+	}
+	defer productConn.Close()
+	productClient := pbProduct.NewProductServiceClient(productConn)
+
 	// 创建带有 JWT 中间件的 gRPC 服务器
 	s := grpc.NewServer(
 		grpc.UnaryInterceptor(middleware.JWTMiddleware),
 	)
-	userService := service.NewUserService(db)
+	userService := service.NewUserService(db, productClient)
 	pb.RegisterUserServiceServer(s, userService)
 
+	// 消费商品事件，记录库存预警并发送到货和降价提醒
+	productEventTopic := os.Getenv("PRODUCT_EVENT_TOPIC")
+	if productEventTopic == "" {
+		productEventTopic = "product-events"
+	}
+	userService.StartProductEventConsumer(strings.Split(os.Getenv("KAFKA_BROKERS"), ","), productEventTopic)
+
 	log.Printf("Server listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// RefreshToken 服务端保存的 refresh token，只保存哈希值。每次刷新后旧 token 失效并指向新 token
+type RefreshToken struct {
+	ID         uint       `gorm:"primarykey"`
+	UserID     int64      `gorm:"not null;index"`
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex"` // token 的 SHA-256
+	ExpiresAt  time.Time  `gorm:"not null"`
+	RevokedAt  *time.Time // 刷新、退出登录或修改密码时吊销
+	ReplacedBy uint       `gorm:"not null;default:0"` // 刷新后换成的新 token，非 0 表示已被使用过
+	CreatedAt  time.Time
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// StockAlert 库存预警，由商品事件消费者写入
+type StockAlert struct {
+	ID          uint   `gorm:"primarykey"`
+	EventID     uint64 `gorm:"not null;uniqueIndex"` // 商品事件ID，重复消费时去重
+	Type        int    `gorm:"not null;index"`       // 对应 pb.StockAlertType
+	ProductID   int64  `gorm:"not null;index"`
+	SkuID       int64  `gorm:"not null;default:0"`
+	WarehouseID int64  `gorm:"not null;default:0"`
+	Stock       int    `gorm:"not null"`
+	Threshold   int    `gorm:"not null;default:0"`
+	Reference   string `gorm:"size:64"`
+	OccurredAt  time.Time
+	CreatedAt   time.Time
+}
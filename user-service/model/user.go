@@ -9,4 +9,5 @@ type User struct {
 	Username string `gorm:"uniqueIndex;size:32;not null"`
 	Password string `gorm:"size:128;not null"`
 	Email    string `gorm:"size:128;not null"`
+	Role     string `gorm:"size:16;not null;default:customer"` // customer / support / admin
 }
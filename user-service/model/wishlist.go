@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// WishlistItem 收藏的商品
+type WishlistItem struct {
+	ID        uint  `gorm:"primarykey"`
+	UserID    int64 `gorm:"not null;uniqueIndex:idx_wishlist_user_product"`
+	ProductID int64 `gorm:"not null;uniqueIndex:idx_wishlist_user_product;index"`
+	CreatedAt time.Time
+}
+
+// ProductSubscription 商品或规格的到货或降价提醒订阅
+type ProductSubscription struct {
+	ID             uint    `gorm:"primarykey"`
+	UserID         int64   `gorm:"not null;uniqueIndex:idx_subscription_user_product_sku"`
+	ProductID      int64   `gorm:"not null;uniqueIndex:idx_subscription_user_product_sku;index"`
+	SkuID          int64   `gorm:"not null;default:0;uniqueIndex:idx_subscription_user_product_sku"` // 0 表示整个商品
+	Type           int     `gorm:"not null;uniqueIndex:idx_subscription_user_product_sku"`           // 对应 pb.SubscriptionType
+	ReferencePrice float64 `gorm:"not null;default:0"`                                               // 降价提醒的参考价格，每次提醒后更新为新价格
+	CreatedAt      time.Time
+}
+
+// Notification 用户通知，同一商品事件对同一用户每种类型只产生一条
+type Notification struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    int64  `gorm:"not null;index;uniqueIndex:idx_notification_event_user"`
+	EventID   uint64 `gorm:"not null;uniqueIndex:idx_notification_event_user"`
+	Type      int    `gorm:"not null;uniqueIndex:idx_notification_event_user"` // 对应 pb.NotificationType
+	ProductID int64  `gorm:"not null"`
+	Title     string `gorm:"size:128;not null"`
+	Content   string `gorm:"size:512"`
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"common/middleware"
+	pbProduct "common/proto/gen/product"
+	pb "common/proto/gen/user"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"user-service/model"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxStockAlertPageSize     = 100
+	defaultStockAlertPageSize = 20
+
+	maxNotificationPageSize     = 100
+	defaultNotificationPageSize = 20
+)
+
+// productEventJSON 商品快照的解码方式，忽略新增的字段
+var productEventJSON = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// stockAlertEvents 商品事件中的库存预警类型
+var stockAlertEvents = map[string]pb.StockAlertType{
+	"product_low_stock":    pb.StockAlertType_STOCK_ALERT_LOW,
+	"product_out_of_stock": pb.StockAlertType_STOCK_ALERT_OUT_OF_STOCK,
+}
+
+// productEvent 商品服务发送的商品事件，before 和 after 为商品快照或库存，movement 为库存流水
+type productEvent struct {
+	ID         uint64          `json:"id"`
+	Event      string          `json:"event"`
+	ProductID  int64           `json:"product_id"`
+	OccurredAt string          `json:"occurred_at"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Movement   json.RawMessage `json:"movement"`
+}
+
+// stockLevel 库存变化和预警事件中的库存
+type stockLevel struct {
+	Stock             int `json:"stock"`
+	ProductStock      int `json:"product_stock"`
+	WarehouseStock    int `json:"warehouse_stock"`
+	LowStockThreshold int `json:"low_stock_threshold"`
+}
+
+// StartProductEventConsumer 消费商品事件，记录库存预警并发送到货和降价提醒
+func (s *UserService) StartProductEventConsumer(brokers []string, topic string) {
+	go func() {
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			Topic:    topic,
+			GroupID:  "user-service-notifications",
+			MinBytes: 1,
+			MaxBytes: 10e6,
+		})
+		defer r.Close()
+		fmt.Println("[Kafka] 用户服务商品事件消费者已启动...")
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			m, err := r.ReadMessage(ctx)
+			cancel()
+			if err != nil {
+				if ctx.Err() != context.DeadlineExceeded {
+					log.Println("Kafka 消费出错:", err)
+				}
+				continue
+			}
+			if err := s.handleProductEvent(m.Value); err != nil {
+				log.Printf("处理商品事件失败: %v, 消息: %s", err, string(m.Value))
+			}
+		}
+	}()
+}
+
+// handleProductEvent 处理商品事件，其他类型的事件忽略
+func (s *UserService) handleProductEvent(data []byte) error {
+	var event productEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	if alertType, ok := stockAlertEvents[event.Event]; ok {
+		return s.recordStockAlert(&event, alertType)
+	}
+	switch event.Event {
+	case "product_stock_changed":
+		return s.notifyBackInStock(&event)
+	case "product_updated":
+		return s.handleProductUpdated(&event)
+	}
+	return nil
+}
+
+// handleProductUpdated 商品修改后发送降价提醒；未上架的商品上架时如果有库存，补发之前跳过的到货提醒
+func (s *UserService) handleProductUpdated(event *productEvent) error {
+	var before, after pbProduct.Product
+	if err := productEventJSON.Unmarshal(event.Before, &before); err != nil {
+		return fmt.Errorf("invalid product snapshot: %w", err)
+	}
+	if err := productEventJSON.Unmarshal(event.After, &after); err != nil {
+		return fmt.Errorf("invalid product snapshot: %w", err)
+	}
+	if after.Status != pbProduct.ProductStatus_PRODUCT_STATUS_ACTIVE {
+		return nil
+	}
+	if before.Status != pbProduct.ProductStatus_PRODUCT_STATUS_ACTIVE {
+		if skuIDs := inStockSkuIDs(&after); len(skuIDs) > 0 {
+			if err := s.sendBackInStock(event, &after, skuIDs); err != nil {
+				return err
+			}
+		}
+	}
+	return s.notifyPriceDrop(event, &before, &after)
+}
+
+// notifyBackInStock 规格的库存从无到有时通知订阅该规格的用户，商品的总库存从无到有时通知订阅整个商品的用户
+func (s *UserService) notifyBackInStock(event *productEvent) error {
+	var before, after stockLevel
+	if err := json.Unmarshal(event.Before, &before); err != nil {
+		return fmt.Errorf("invalid stock level: %w", err)
+	}
+	if err := json.Unmarshal(event.After, &after); err != nil {
+		return fmt.Errorf("invalid stock level: %w", err)
+	}
+	var movement pbProduct.StockMovement
+	if err := productEventJSON.Unmarshal(event.Movement, &movement); err != nil {
+		return fmt.Errorf("invalid stock movement: %w", err)
+	}
+	var skuIDs []int64
+	if movement.SkuId > 0 {
+		if before.Stock <= 0 && after.Stock > 0 {
+			skuIDs = append(skuIDs, movement.SkuId)
+		}
+		if before.ProductStock <= 0 && after.ProductStock > 0 {
+			skuIDs = append(skuIDs, 0)
+		}
+	} else if before.Stock <= 0 && after.Stock > 0 {
+		skuIDs = append(skuIDs, 0)
+	}
+	if len(skuIDs) == 0 {
+		return nil
+	}
+	// 未上架的商品暂不通知，订阅保留到商品上架时由 handleProductUpdated 补发
+	product, err := s.activeProduct(context.Background(), event.ProductID)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.sendBackInStock(event, product, skuIDs)
+}
+
+// inStockSkuIDs 有库存的规格ID，整个商品有库存时包含 0
+func inStockSkuIDs(p *pbProduct.Product) []int64 {
+	var skuIDs []int64
+	if productInStock(p) {
+		skuIDs = append(skuIDs, 0)
+	}
+	for _, sku := range p.Skus {
+		if sku.Stock > 0 {
+			skuIDs = append(skuIDs, sku.Id)
+		}
+	}
+	return skuIDs
+}
+
+// sendBackInStock 给订阅 skuIDs 中规格（0 为整个商品）到货提醒的用户发送通知，通知后取消订阅
+func (s *UserService) sendBackInStock(event *productEvent, product *pbProduct.Product, skuIDs []int64) error {
+	var subscriptions []model.ProductSubscription
+	if err := s.db.Where("product_id = ? AND sku_id IN ? AND type = ?",
+		event.ProductID, skuIDs, int(pb.SubscriptionType_SUBSCRIPTION_BACK_IN_STOCK)).
+		Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	notifications := make([]model.Notification, 0, len(subscriptions))
+	ids := make([]uint, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		notifications = append(notifications, model.Notification{
+			UserID:    sub.UserID,
+			EventID:   event.ID,
+			Type:      int(pb.NotificationType_NOTIFICATION_BACK_IN_STOCK),
+			ProductID: event.ProductID,
+			Title:     "您关注的商品到货了",
+			Content:   fmt.Sprintf("%s 已到货，欢迎选购", subscriptionName(product, sub.SkuID)),
+		})
+		ids = append(ids, sub.ID)
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := createNotifications(tx, notifications); err != nil {
+			return err
+		}
+		return tx.Delete(&model.ProductSubscription{}, ids).Error
+	})
+}
+
+// notifyPriceDrop 上架商品降价时，通知参考价格高于新价格的降价提醒订阅者，并将参考价格更新为新价格。
+// 整个商品的订阅按最低价格比较，规格的订阅按规格价格比较
+func (s *UserService) notifyPriceDrop(event *productEvent, before, after *pbProduct.Product) error {
+	if !priceDropped(before, after) {
+		return nil
+	}
+	var subscriptions []model.ProductSubscription
+	if err := s.db.Where("product_id = ? AND type = ?", event.ProductID, int(pb.SubscriptionType_SUBSCRIPTION_PRICE_DROP)).
+		Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	var notifications []model.Notification
+	idsByPrice := make(map[float64][]uint)
+	for _, sub := range subscriptions {
+		price, ok := subscriptionPrice(after, sub.SkuID)
+		if !ok || price >= sub.ReferencePrice {
+			continue
+		}
+		if old, ok := subscriptionPrice(before, sub.SkuID); ok && price >= old {
+			continue
+		}
+		notifications = append(notifications, model.Notification{
+			UserID:    sub.UserID,
+			EventID:   event.ID,
+			Type:      int(pb.NotificationType_NOTIFICATION_PRICE_DROP),
+			ProductID: event.ProductID,
+			Title:     "您关注的商品降价了",
+			Content:   fmt.Sprintf("%s 降价至 %.2f（之前为 %.2f）", subscriptionName(after, sub.SkuID), price, sub.ReferencePrice),
+		})
+		idsByPrice[price] = append(idsByPrice[price], sub.ID)
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := createNotifications(tx, notifications); err != nil {
+			return err
+		}
+		for price, ids := range idsByPrice {
+			if err := tx.Model(&model.ProductSubscription{}).Where("id IN ?", ids).Update("reference_price", price).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// priceDropped 商品的最低价格或任一规格的价格是否降低
+func priceDropped(before, after *pbProduct.Product) bool {
+	if lowestPrice(after) < lowestPrice(before) {
+		return true
+	}
+	for _, sku := range after.Skus {
+		if old := findSku(before, sku.Id); old != nil && sku.Price < old.Price {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionName 通知中的商品名称，规格的订阅附带规格属性
+func subscriptionName(p *pbProduct.Product, skuID int64) string {
+	sku := findSku(p, skuID)
+	if skuID == 0 || sku == nil || len(sku.Attributes) == 0 {
+		return p.Name
+	}
+	keys := make([]string, 0, len(sku.Attributes))
+	for k := range sku.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, sku.Attributes[k])
+	}
+	return fmt.Sprintf("%s（%s）", p.Name, strings.Join(values, " "))
+}
+
+// createNotifications 写入通知，重复消费同一事件时忽略已有的通知
+func createNotifications(tx *gorm.DB, notifications []model.Notification) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications).Error
+}
+
+// recordStockAlert 记录库存预警，同一事件只记录一次
+func (s *UserService) recordStockAlert(event *productEvent, alertType pb.StockAlertType) error {
+	var after stockLevel
+	if err := json.Unmarshal(event.After, &after); err != nil {
+		return fmt.Errorf("invalid stock level: %w", err)
+	}
+	var movement pbProduct.StockMovement
+	if err := productEventJSON.Unmarshal(event.Movement, &movement); err != nil {
+		return fmt.Errorf("invalid stock movement: %w", err)
+	}
+	occurredAt, err := time.Parse(time.RFC3339, event.OccurredAt)
+	if err != nil {
+		occurredAt = time.Now()
+	}
+	alert := model.StockAlert{
+		EventID:     event.ID,
+		Type:        int(alertType),
+		ProductID:   event.ProductID,
+		SkuID:       movement.SkuId,
+		WarehouseID: movement.WarehouseId,
+		Stock:       after.Stock,
+		Threshold:   after.LowStockThreshold,
+		Reference:   movement.Reference,
+		OccurredAt:  occurredAt,
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&alert).Error
+}
+
+// ListStockAlerts 分页查询库存预警，最新的在前
+func (s *UserService) ListStockAlerts(ctx context.Context, req *pb.ListStockAlertsRequest) (*pb.ListStockAlertsResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin); err != nil {
+		return nil, err
+	}
+	page := int(req.Page)
+	pageSize := int(req.PageSize)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultStockAlertPageSize
+	}
+	if pageSize > maxStockAlertPageSize {
+		pageSize = maxStockAlertPageSize
+	}
+
+	q := s.db.Model(&model.StockAlert{})
+	if req.ProductId > 0 {
+		q = q.Where("product_id = ?", req.ProductId)
+	}
+	if len(req.Types) > 0 {
+		types := make([]int, 0, len(req.Types))
+		for _, t := range req.Types {
+			types = append(types, int(t))
+		}
+		q = q.Where("type IN ?", types)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to count stock alerts")
+	}
+	var alerts []model.StockAlert
+	if err := q.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&alerts).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query stock alerts")
+	}
+	resp := &pb.ListStockAlertsResponse{Total: int32(total)}
+	for i := range alerts {
+		resp.Alerts = append(resp.Alerts, convertStockAlertToPB(&alerts[i]))
+	}
+	return resp, nil
+}
+
+// ListNotifications 当前用户的通知，最新的在前
+func (s *UserService) ListNotifications(ctx context.Context, req *pb.ListNotificationsRequest) (*pb.ListNotificationsResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page := int(req.Page)
+	pageSize := int(req.PageSize)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultNotificationPageSize
+	}
+	if pageSize > maxNotificationPageSize {
+		pageSize = maxNotificationPageSize
+	}
+
+	var unread int64
+	if err := s.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to count notifications")
+	}
+	q := s.db.Model(&model.Notification{}).Where("user_id = ?", userID)
+	if req.UnreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to count notifications")
+	}
+	var notifications []model.Notification
+	if err := q.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query notifications")
+	}
+	resp := &pb.ListNotificationsResponse{Total: int32(total), Unread: int32(unread)}
+	for i := range notifications {
+		resp.Notifications = append(resp.Notifications, convertNotificationToPB(&notifications[i]))
+	}
+	return resp, nil
+}
+
+// MarkNotificationsRead 将当前用户的通知标记为已读
+func (s *UserService) MarkNotificationsRead(ctx context.Context, req *pb.MarkNotificationsReadRequest) (*pb.MarkNotificationsReadResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := s.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(req.NotificationIds) > 0 {
+		q = q.Where("id IN ?", req.NotificationIds)
+	}
+	if err := q.Update("read_at", time.Now()).Error; err != nil {
+		return &pb.MarkNotificationsReadResponse{Success: false, Message: "failed to mark notifications as read"}, nil
+	}
+	return &pb.MarkNotificationsReadResponse{Success: true, Message: "notifications marked as read"}, nil
+}
+
+func convertNotificationToPB(n *model.Notification) *pb.Notification {
+	return &pb.Notification{
+		Id:        int64(n.ID),
+		Type:      pb.NotificationType(n.Type),
+		ProductId: n.ProductID,
+		Title:     n.Title,
+		Content:   n.Content,
+		Read:      n.ReadAt != nil,
+		CreatedAt: n.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func convertStockAlertToPB(a *model.StockAlert) *pb.StockAlert {
+	return &pb.StockAlert{
+		Id:          int64(a.ID),
+		Type:        pb.StockAlertType(a.Type),
+		ProductId:   a.ProductID,
+		SkuId:       a.SkuID,
+		WarehouseId: a.WarehouseID,
+		Stock:       int32(a.Stock),
+		Threshold:   int32(a.Threshold),
+		Reference:   a.Reference,
+		CreatedAt:   a.OccurredAt.Format(time.RFC3339),
+	}
+}
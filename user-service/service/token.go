@@ -0,0 +1,151 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/user"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+	"user-service/model"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// refreshTokenTTL refresh token 的有效期
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// errRefreshTokenUsed 刷新时 refresh token 已被并发请求使用
+var errRefreshTokenUsed = errors.New("refresh token already used")
+
+// RefreshToken 用 refresh token 换取新的 access token 和 refresh token。
+// 已经换过的 refresh token 再次使用说明可能被盗用，吊销该用户的所有 token
+func (s *UserService) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+	var old model.RefreshToken
+	if err := s.db.Where("token_hash = ?", hashRefreshToken(req.RefreshToken)).First(&old).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+		return nil, status.Error(codes.Internal, "failed to query refresh token")
+	}
+	if old.RevokedAt != nil {
+		if old.ReplacedBy != 0 {
+			log.Printf("Refresh token %d of user %d reused, revoking all tokens", old.ID, old.UserID)
+			if err := s.revokeAllTokens(ctx, s.db, old.UserID); err != nil {
+				log.Printf("Failed to revoke tokens of user %d: %v", old.UserID, err)
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, "refresh token has been revoked")
+	}
+	if time.Now().After(old.ExpiresAt) {
+		return nil, status.Error(codes.Unauthenticated, "refresh token has expired")
+	}
+	var user model.User
+	if err := s.db.First(&user, old.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to query user")
+	}
+
+	var refreshToken string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		token, record, err := createRefreshToken(tx, old.UserID)
+		if err != nil {
+			return err
+		}
+		// 只有一个并发请求能换成功
+		result := tx.Model(&model.RefreshToken{}).Where("id = ? AND revoked_at IS NULL", old.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by": record.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenUsed
+		}
+		refreshToken = token
+		return nil
+	})
+	if errors.Is(err, errRefreshTokenUsed) {
+		return nil, status.Error(codes.Unauthenticated, "refresh token has been revoked")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to refresh token")
+	}
+	accessToken, err := middleware.GenerateToken(int64(user.ID), user.Username, user.Role)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate token")
+	}
+	return &pb.RefreshTokenResponse{
+		UserId:       int64(user.ID),
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// Logout 吊销请求携带的 access token 和给出的 refresh token。
+// access token 已过期时仍可以只凭 refresh token 退出登录
+func (s *UserService) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	claims, authenticated := middleware.ClaimsFromContext(ctx)
+	if !authenticated && req.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+	if req.RefreshToken != "" {
+		if err := s.db.Model(&model.RefreshToken{}).
+			Where("token_hash = ? AND revoked_at IS NULL", hashRefreshToken(req.RefreshToken)).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return &pb.LogoutResponse{Success: false, Message: "failed to revoke refresh token"}, nil
+		}
+	}
+	if authenticated {
+		if err := middleware.RevokeToken(ctx, claims); err != nil {
+			return &pb.LogoutResponse{Success: false, Message: "failed to revoke token"}, nil
+		}
+	}
+	return &pb.LogoutResponse{Success: true, Message: "logout successful"}, nil
+}
+
+// createRefreshToken 为用户签发新的 refresh token，同时清理该用户已过期的 token
+func createRefreshToken(tx *gorm.DB, userID int64) (string, *model.RefreshToken, error) {
+	if err := tx.Where("user_id = ? AND expires_at < ?", userID, time.Now()).Delete(&model.RefreshToken{}).Error; err != nil {
+		return "", nil, err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	record := &model.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+// revokeAllTokens 吊销用户所有的 refresh token 和已签发的 access token
+func (s *UserService) revokeAllTokens(ctx context.Context, tx *gorm.DB, userID int64) error {
+	if err := tx.Model(&model.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return middleware.RevokeUserTokens(ctx, userID)
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
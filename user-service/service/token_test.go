@@ -0,0 +1,191 @@
+package service
+
+import (
+	"common/middleware"
+	pb "common/proto/gen/user"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+	"user-service/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 内存中的 SQLite 数据库，每个测试独立
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库只存在于单个连接中
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.User{}, &model.RefreshToken{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestDenylist 将吊销记录写入内存中的 Redis，测试结束后恢复
+func newTestDenylist(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	middleware.SetTokenDenylist(rdb)
+	t.Cleanup(func() {
+		middleware.SetTokenDenylist(nil)
+		rdb.Close()
+	})
+	return mr
+}
+
+// newTestUserService 创建用户 1 和 2 的服务
+func newTestUserService(t *testing.T) *UserService {
+	t.Helper()
+	db := newTestDB(t)
+	users := []model.User{
+		{Model: gorm.Model{ID: 1}, Username: "alice", Password: "x", Email: "alice@example.com", Role: middleware.RoleCustomer},
+		{Model: gorm.Model{ID: 2}, Username: "bob", Password: "x", Email: "bob@example.com", Role: middleware.RoleCustomer},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &UserService{db: db}
+}
+
+func issueTestRefreshToken(t *testing.T, s *UserService, userID int64) (string, *model.RefreshToken) {
+	t.Helper()
+	token, record, err := createRefreshToken(s.db, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, record
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	s := newTestUserService(t)
+	token, old := issueTestRefreshToken(t, s, 1)
+
+	resp, err := s.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: token})
+	if err != nil {
+		t.Fatalf("RefreshToken error = %v", err)
+	}
+	if resp.UserId != 1 || resp.Token == "" || resp.RefreshToken == "" || resp.RefreshToken == token {
+		t.Fatalf("RefreshToken = %+v, want a new token pair for user 1", resp)
+	}
+	if _, err := middleware.ParseToken(resp.Token); err != nil {
+		t.Errorf("access token is invalid: %v", err)
+	}
+
+	var rotated, next model.RefreshToken
+	s.db.First(&rotated, old.ID)
+	s.db.Where("token_hash = ?", hashRefreshToken(resp.RefreshToken)).First(&next)
+	if rotated.RevokedAt == nil || rotated.ReplacedBy != next.ID {
+		t.Errorf("old token revoked_at = %v replaced_by = %d, want revoked and replaced by %d", rotated.RevokedAt, rotated.ReplacedBy, next.ID)
+	}
+	if next.RevokedAt != nil {
+		t.Error("new token is revoked")
+	}
+
+	// 新 token 可以继续刷新
+	if _, err := s.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: resp.RefreshToken}); err != nil {
+		t.Errorf("RefreshToken with new token error = %v", err)
+	}
+}
+
+func TestRefreshTokenRejected(t *testing.T) {
+	now := time.Now()
+	unknown := "unknown"
+	tests := []struct {
+		name     string
+		token    *string // 不为 nil 时替换签发的 token
+		update   map[string]interface{}
+		wantCode codes.Code
+	}{
+		{name: "missing token", token: new(string), wantCode: codes.InvalidArgument},
+		{name: "unknown token", token: &unknown, wantCode: codes.Unauthenticated},
+		{name: "expired", update: map[string]interface{}{"expires_at": now.Add(-time.Minute)}, wantCode: codes.Unauthenticated},
+		{name: "logged out", update: map[string]interface{}{"revoked_at": now}, wantCode: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestUserService(t)
+			token, record := issueTestRefreshToken(t, s, 1)
+			_, other := issueTestRefreshToken(t, s, 1)
+			if tt.update != nil {
+				if err := s.db.Model(record).Updates(tt.update).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.token != nil {
+				token = *tt.token
+			}
+			_, err := s.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: token})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("RefreshToken error = %v, want %v", err, tt.wantCode)
+			}
+			// 未被换过的 token 不触发吊销全部
+			s.db.First(other, other.ID)
+			if other.RevokedAt != nil {
+				t.Error("other token of the user is revoked")
+			}
+		})
+	}
+}
+
+func TestRefreshTokenReuseRevokesAll(t *testing.T) {
+	mr := newTestDenylist(t)
+	s := newTestUserService(t)
+	ctx := context.Background()
+	stolen, _ := issueTestRefreshToken(t, s, 1)
+	issueTestRefreshToken(t, s, 1) // 另一台设备登录
+	issueTestRefreshToken(t, s, 2)
+
+	resp, err := s.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: stolen})
+	if err != nil {
+		t.Fatalf("RefreshToken error = %v", err)
+	}
+	_, err = s.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: stolen})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("reuse RefreshToken error = %v, want Unauthenticated", err)
+	}
+
+	tests := []struct {
+		name        string
+		userID      int64
+		wantRevoked bool
+	}{
+		{name: "reused user", userID: 1, wantRevoked: true},
+		{name: "other user", userID: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var active int64
+			s.db.Model(&model.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", tt.userID).Count(&active)
+			if (active == 0) != tt.wantRevoked {
+				t.Errorf("active refresh tokens = %d, want revoked %v", active, tt.wantRevoked)
+			}
+			key := "jwt:revoked-user:" + strconv.FormatInt(tt.userID, 10)
+			if mr.Exists(key) != tt.wantRevoked {
+				t.Errorf("key %s exists = %v, want %v", key, mr.Exists(key), tt.wantRevoked)
+			}
+		})
+	}
+
+	// 刷新得到的新 token 也已失效
+	if _, err := s.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: resp.RefreshToken}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("RefreshToken with rotated token error = %v, want Unauthenticated", err)
+	}
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"common/middleware"
+	pbProduct "common/proto/gen/product"
 	pb "common/proto/gen/user"
 	"context"
 	"errors"
+	"time"
 	"user-service/model"
 
 	"golang.org/x/crypto/bcrypt"
@@ -15,11 +17,12 @@ import (
 
 type UserService struct {
 	pb.UnimplementedUserServiceServer
-	db *gorm.DB
+	db            *gorm.DB
+	productClient pbProduct.ProductServiceClient
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(db *gorm.DB, productClient pbProduct.ProductServiceClient) *UserService {
+	return &UserService{db: db, productClient: productClient}
 }
 
 func (s *UserService) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
@@ -40,6 +43,7 @@ func (s *UserService) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 		Username: req.Username,
 		Password: string(hashedPassword),
 		Email:    req.Email,
+		Role:     middleware.RoleCustomer,
 	}
 
 	if err := s.db.Create(&user).Error; err != nil {
@@ -67,15 +71,21 @@ func (s *UserService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Logi
 	}
 
 	// 生成JWT token
-	token, err := middleware.GenerateToken(int64(user.ID), user.Username)
+	token, err := middleware.GenerateToken(int64(user.ID), user.Username, user.Role)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to generate token")
 	}
+	refreshToken, _, err := createRefreshToken(s.db, int64(user.ID))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate refresh token")
+	}
 
 	return &pb.LoginResponse{
-		UserId:  int64(user.ID),
-		Token:   token,
-		Message: "login successful",
+		UserId:       int64(user.ID),
+		Token:        token,
+		Message:      "login successful",
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL / time.Second),
 	}, nil
 }
 
@@ -116,7 +126,17 @@ func (s *UserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 		}
 		user.Password = string(hashedPassword)
 	}
-	if err := s.db.Save(&user).Error; err != nil {
+	// 修改密码后吊销所有已签发的 token，需要重新登录
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		if req.Password != "" {
+			return s.revokeAllTokens(ctx, tx, int64(user.ID))
+		}
+		return nil
+	})
+	if err != nil {
 		return &pb.UpdateUserResponse{Success: false, Message: "failed to update user"}, nil
 	}
 	return &pb.UpdateUserResponse{Success: true, Message: "user updated successfully"}, nil
@@ -163,3 +183,45 @@ func (s *UserService) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (
 		Message: "success",
 	}, nil
 }
+
+// LookupUsers 按ID、用户名或邮箱批量查询用户，仅客服和管理员可调用
+func (s *UserService) LookupUsers(ctx context.Context, req *pb.LookupUsersRequest) (*pb.LookupUsersResponse, error) {
+	if err := middleware.RequireRole(ctx, middleware.RoleAdmin, middleware.RoleSupport); err != nil {
+		return nil, err
+	}
+	if len(req.UserIds) == 0 && req.Username == "" {
+		return &pb.LookupUsersResponse{}, nil
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 100
+	}
+	dbQuery := s.db.Model(&model.User{})
+	if len(req.UserIds) > 0 {
+		dbQuery = dbQuery.Where("id IN ?", req.UserIds)
+	}
+	if req.Username != "" {
+		keyword := "%" + req.Username + "%"
+		dbQuery = dbQuery.Where("(username LIKE ? OR email LIKE ?)", keyword, keyword)
+	}
+	// 多取一条用于判断结果是否被截断
+	var users []model.User
+	if err := dbQuery.Limit(limit + 1).Order("id desc").Find(&users).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to lookup users")
+	}
+	resp := &pb.LookupUsersResponse{}
+	if len(users) > limit {
+		users = users[:limit]
+		resp.Truncated = true
+	}
+	for _, u := range users {
+		resp.Users = append(resp.Users, &pb.UserInfo{
+			UserId:    int64(u.ID),
+			Username:  u.Username,
+			Email:     u.Email,
+			CreatedAt: u.CreatedAt.Format("2006-01-02 15:04:05"),
+			UpdatedAt: u.UpdatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return resp, nil
+}
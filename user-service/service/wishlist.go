@@ -0,0 +1,258 @@
+package service
+
+import (
+	"common/middleware"
+	pbProduct "common/proto/gen/product"
+	pb "common/proto/gen/user"
+	"context"
+	"time"
+	"user-service/model"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm/clause"
+)
+
+// maxWishlistItems 每个用户收藏的商品数量上限，与商品服务批量查询的上限一致
+const maxWishlistItems = 100
+
+// AddWishlistItem 收藏上架中的商品
+func (s *UserService) AddWishlistItem(ctx context.Context, req *pb.AddWishlistItemRequest) (*pb.AddWishlistItemResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.activeProduct(ctx, req.ProductId); err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := s.db.Model(&model.WishlistItem{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to count wishlist items")
+	}
+	if count >= maxWishlistItems {
+		return nil, status.Errorf(codes.FailedPrecondition, "a wishlist can have at most %d items", maxWishlistItems)
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.WishlistItem{UserID: userID, ProductID: req.ProductId})
+	if result.Error != nil {
+		return nil, status.Error(codes.Internal, "failed to add wishlist item")
+	}
+	if result.RowsAffected == 0 {
+		return &pb.AddWishlistItemResponse{Success: true, Message: "product already in wishlist"}, nil
+	}
+	return &pb.AddWishlistItemResponse{Success: true, Message: "product added to wishlist"}, nil
+}
+
+// RemoveWishlistItem 取消收藏
+func (s *UserService) RemoveWishlistItem(ctx context.Context, req *pb.RemoveWishlistItemRequest) (*pb.RemoveWishlistItemResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := s.db.Where("user_id = ? AND product_id = ?", userID, req.ProductId).Delete(&model.WishlistItem{})
+	if result.Error != nil {
+		return &pb.RemoveWishlistItemResponse{Success: false, Message: "failed to remove wishlist item"}, nil
+	}
+	if result.RowsAffected == 0 {
+		return &pb.RemoveWishlistItemResponse{Success: false, Message: "product not in wishlist"}, nil
+	}
+	return &pb.RemoveWishlistItemResponse{Success: true, Message: "product removed from wishlist"}, nil
+}
+
+// ListWishlist 当前用户的收藏夹，商品信息从商品服务实时获取
+func (s *UserService) ListWishlist(ctx context.Context, req *pb.ListWishlistRequest) (*pb.ListWishlistResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var items []model.WishlistItem
+	if err := s.db.Where("user_id = ?", userID).Order("id desc").Limit(maxWishlistItems).Find(&items).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query wishlist")
+	}
+	resp := &pb.ListWishlistResponse{}
+	if len(items) == 0 {
+		return resp, nil
+	}
+	ids := make([]int64, 0, len(items))
+	for _, it := range items {
+		ids = append(ids, it.ProductID)
+	}
+	// 不透传调用方身份，未上架的商品视为不存在
+	products, err := s.productClient.BatchGetProducts(ctx, &pbProduct.BatchGetProductsRequest{ProductIds: ids})
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to get product info: %v", err)
+	}
+	byID := make(map[int64]*pbProduct.Product, len(products.Products))
+	for _, p := range products.Products {
+		byID[p.Id] = p
+	}
+	for _, it := range items {
+		item := &pb.WishlistItem{ProductId: it.ProductID, AddedAt: it.CreatedAt.Format(time.RFC3339)}
+		if p, ok := byID[it.ProductID]; ok {
+			item.Name = p.Name
+			item.Price = lowestPrice(p)
+			item.MainImage = p.MainImage
+			item.InStock = productInStock(p)
+			item.Available = true
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, nil
+}
+
+// SubscribeProduct 订阅商品或某个规格的到货或降价提醒，降价提醒以订阅时的价格为参考价格
+func (s *UserService) SubscribeProduct(ctx context.Context, req *pb.SubscribeProductRequest) (*pb.SubscribeProductResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := pb.SubscriptionType_name[int32(req.Type)]; !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid subscription type")
+	}
+	product, err := s.activeProduct(ctx, req.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	referencePrice, ok := subscriptionPrice(product, req.SkuId)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "sku not found")
+	}
+	if req.Type == pb.SubscriptionType_SUBSCRIPTION_BACK_IN_STOCK && subscriptionInStock(product, req.SkuId) {
+		return nil, status.Error(codes.FailedPrecondition, "product is in stock")
+	}
+	subscription := model.ProductSubscription{
+		UserID:         userID,
+		ProductID:      req.ProductId,
+		SkuID:          req.SkuId,
+		Type:           int(req.Type),
+		ReferencePrice: referencePrice,
+	}
+	if err := s.db.Where("user_id = ? AND product_id = ? AND sku_id = ? AND type = ?", userID, req.ProductId, req.SkuId, int(req.Type)).
+		FirstOrCreate(&subscription).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to subscribe product")
+	}
+	return &pb.SubscribeProductResponse{Subscription: convertSubscriptionToPB(&subscription)}, nil
+}
+
+// UnsubscribeProduct 取消订阅
+func (s *UserService) UnsubscribeProduct(ctx context.Context, req *pb.UnsubscribeProductRequest) (*pb.UnsubscribeProductResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := s.db.Where("user_id = ? AND product_id = ? AND sku_id = ? AND type = ?", userID, req.ProductId, req.SkuId, int(req.Type)).
+		Delete(&model.ProductSubscription{})
+	if result.Error != nil {
+		return &pb.UnsubscribeProductResponse{Success: false, Message: "failed to unsubscribe product"}, nil
+	}
+	if result.RowsAffected == 0 {
+		return &pb.UnsubscribeProductResponse{Success: false, Message: "subscription not found"}, nil
+	}
+	return &pb.UnsubscribeProductResponse{Success: true, Message: "product unsubscribed"}, nil
+}
+
+// ListSubscriptions 当前用户的订阅，最新的在前
+func (s *UserService) ListSubscriptions(ctx context.Context, req *pb.ListSubscriptionsRequest) (*pb.ListSubscriptionsResponse, error) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var subscriptions []model.ProductSubscription
+	if err := s.db.Where("user_id = ?", userID).Order("id desc").Find(&subscriptions).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to query subscriptions")
+	}
+	resp := &pb.ListSubscriptionsResponse{}
+	for i := range subscriptions {
+		resp.Subscriptions = append(resp.Subscriptions, convertSubscriptionToPB(&subscriptions[i]))
+	}
+	return resp, nil
+}
+
+// activeProduct 查询上架中的商品。不透传调用方身份，未上架的商品视为不存在
+func (s *UserService) activeProduct(ctx context.Context, productID int64) (*pbProduct.Product, error) {
+	if productID <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "product_id is required")
+	}
+	resp, err := s.productClient.BatchGetProducts(ctx, &pbProduct.BatchGetProductsRequest{ProductIds: []int64{productID}})
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to get product info: %v", err)
+	}
+	if len(resp.Products) == 0 {
+		return nil, status.Error(codes.NotFound, "product not found")
+	}
+	return resp.Products[0], nil
+}
+
+// currentUserID 当前登录的用户
+func currentUserID(ctx context.Context) (int64, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+	return claims.UserID, nil
+}
+
+// lowestPrice 商品的最低价格，多规格商品为最低的规格价格
+func lowestPrice(p *pbProduct.Product) float64 {
+	price := p.Price
+	for i, sku := range p.Skus {
+		if i == 0 || sku.Price < price {
+			price = sku.Price
+		}
+	}
+	return price
+}
+
+// productInStock 商品或其任一规格有库存
+func productInStock(p *pbProduct.Product) bool {
+	if p.Stock > 0 {
+		return true
+	}
+	for _, sku := range p.Skus {
+		if sku.Stock > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// findSku 商品中指定ID的规格，不存在时返回 nil
+func findSku(p *pbProduct.Product, skuID int64) *pbProduct.Sku {
+	for _, sku := range p.Skus {
+		if sku.Id == skuID {
+			return sku
+		}
+	}
+	return nil
+}
+
+// subscriptionPrice 订阅关注的价格：规格的订阅为规格价格，整个商品的订阅为最低价格。规格不存在时返回 false
+func subscriptionPrice(p *pbProduct.Product, skuID int64) (float64, bool) {
+	if skuID == 0 {
+		return lowestPrice(p), true
+	}
+	sku := findSku(p, skuID)
+	if sku == nil {
+		return 0, false
+	}
+	return sku.Price, true
+}
+
+// subscriptionInStock 订阅关注的规格或整个商品是否有库存
+func subscriptionInStock(p *pbProduct.Product, skuID int64) bool {
+	if skuID == 0 {
+		return productInStock(p)
+	}
+	sku := findSku(p, skuID)
+	return sku != nil && sku.Stock > 0
+}
+
+func convertSubscriptionToPB(sub *model.ProductSubscription) *pb.ProductSubscription {
+	return &pb.ProductSubscription{
+		Id:             int64(sub.ID),
+		ProductId:      sub.ProductID,
+		SkuId:          sub.SkuID,
+		Type:           pb.SubscriptionType(sub.Type),
+		ReferencePrice: sub.ReferencePrice,
+		CreatedAt:      sub.CreatedAt.Format(time.RFC3339),
+	}
+}